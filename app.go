@@ -1,7 +1,17 @@
 package app
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/shoplineapp/go-app/plugins"
+	"github.com/shoplineapp/go-app/plugins/env"
+	"github.com/shoplineapp/go-app/plugins/logger"
+	"github.com/sirupsen/logrus"
 	"go.uber.org/fx"
 	"go.uber.org/fx/fxevent"
 
@@ -12,49 +22,263 @@ type Application struct {
 	name    string
 	fx      *fx.App
 	plugins []interface{}
+	options []fx.Option
+
+	// plugins of the registry are selected unless set by SetPlugins
+	selectPlugins  bool
+	enablePlugins  []string
+	disablePlugins []string
+
+	modules  []AppModuleInterface
+	roles    []*Role
+	role     string
+	commands []Command
+
+	configReport []env.ConfigEntry
 }
 type AppOption struct{}
 
 func NewApplication() *Application {
 	return &Application{
-		plugins: plugins.Registry,
+		plugins:       plugins.Registry,
+		selectPlugins: true,
 	}
 }
 
 func (a *Application) SetPlugins(plugins ...interface{}) {
 	a.plugins = plugins
+	a.selectPlugins = false
+}
+
+// EnablePlugins provides only the registered plugins of the names or tags and their dependencies,
+// in addition to GO_APP_ENABLE_PLUGINS
+func (a *Application) EnablePlugins(names ...string) {
+	a.enablePlugins = append(a.enablePlugins, names...)
+}
+
+// DisablePlugins substitutes the registered plugins of the names or tags with their noop implementations,
+// in addition to GO_APP_DISABLE_PLUGINS, e.g. GO_APP_DISABLE_PLUGINS=newrelic,pyroscope
+func (a *Application) DisablePlugins(names ...string) {
+	a.disablePlugins = append(a.disablePlugins, names...)
+}
+
+// selectedPlugins returns the plugin constructors to be provided
+func (a *Application) selectedPlugins(enable []string, disable []string) ([]interface{}, error) {
+	if !a.selectPlugins {
+		return a.plugins, nil
+	}
+
+	// selected ahead of the Env plugin, so they are looked up from the process env and env files
+	enable = append(splitNames(env.Lookup("GO_APP_ENABLE_PLUGINS")), enable...)
+	disable = append(splitNames(env.Lookup("GO_APP_DISABLE_PLUGINS")), disable...)
+	for _, name := range append(append([]string{}, enable...), disable...) {
+		if !plugins.Exists(name) {
+			logrus.Warn(fmt.Sprintf("Plugin %q to enable or disable is not registered", name))
+		}
+	}
+
+	return plugins.Select(enable, disable)
+}
+
+// lookupDuration returns the duration of the variable, e.g. 45s or 45 in seconds, looked up ahead of the Env plugin
+func lookupDuration(key string, fallback time.Duration) time.Duration {
+	value := env.Lookup(key)
+	if value == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if seconds, atoiErr := strconv.Atoi(value); atoiErr == nil {
+		duration, err = time.Duration(seconds)*time.Second, nil
+	}
+	if err != nil || duration <= 0 {
+		logrus.Warn(fmt.Sprintf("Invalid duration %s=%s, fallback to %s", key, value, fallback))
+		return fallback
+	}
+	return duration
+}
+
+func splitNames(value string) []string {
+	names := []string{}
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
 }
 
+// AddModule adds the module and its imports to the application, each as an fx.Module
 func (a *Application) AddModule(module AppModuleInterface) {
-	controllers := module.Controllers()
-	provides := module.Provide()
+	a.modules = append(a.modules, module)
+}
+
+// AddOptions appends fx options to the application, e.g. fx.Replace or fx.Decorate of provided plugins
+func (app *Application) AddOptions(options ...fx.Option) {
+	app.options = append(app.options, options...)
+}
+
+// Options returns the fx options of the application and the selected role, with funcs invoked as the entrypoint
+func (app *Application) Options(funcs ...interface{}) fx.Option {
+	role, err := app.selectedRole(false)
+	if err != nil {
+		return fx.Error(err)
+	}
+	return app.build(role, funcs)
+}
 
-	if len(controllers) > 0 {
-		a.plugins = append(a.plugins, module.Controllers()...)
+// appPlan is what the application is built from
+type appPlan struct {
+	provided []interface{}
+	modules  *moduleSet
+	options  []fx.Option
+	invokes  []interface{}
+}
+
+func (app *Application) plan(role *Role, funcs []interface{}) (*appPlan, error) {
+	enable := append([]string{}, app.enablePlugins...)
+	disable := append([]string{}, app.disablePlugins...)
+	modules := append([]AppModuleInterface{}, app.modules...)
+	options := append([]fx.Option{}, app.options...)
+	invokes := append([]interface{}{}, funcs...)
+	if role != nil {
+		enable = append(enable, role.enablePlugins...)
+		disable = append(disable, role.disablePlugins...)
+		modules = append(modules, role.modules...)
+		options = append(options, role.options...)
+		invokes = append(invokes, role.funcs...)
 	}
 
-	if len(provides) > 0 {
-		a.plugins = append(a.plugins, module.Provide()...)
+	provided, err := app.selectedPlugins(enable, disable)
+	if err != nil {
+		return nil, err
 	}
+	set, err := buildModules(modules)
+	if err != nil {
+		return nil, err
+	}
+	return &appPlan{provided: provided, modules: set, options: options, invokes: invokes}, nil
 }
 
-func (app *Application) Run(funcs ...interface{}) {
-	app.fx = fx.New(
-		fx.WithLogger(func() fxevent.Logger { return AppLogger{} }),
+// constructors returns the provided plugins and constructors of modules
+func (p *appPlan) constructors() []interface{} {
+	return append(append([]interface{}{}, p.provided...), p.modules.constructors...)
+}
+
+func (app *Application) build(role *Role, funcs []interface{}) fx.Option {
+	p, err := app.plan(role, funcs)
+	if err != nil {
+		return fx.Error(err)
+	}
+
+	var appLogger AppLogger
+	return fx.Options(
+		fx.WithLogger(func(params appLoggerParams) fxevent.Logger {
+			appLogger = NewAppLogger(lookupDuration("GO_APP_HOOK_BUDGET", DefaultHookBudget))
+			if params.Logger != nil {
+				appLogger.Log = params.Logger
+			}
+			return appLogger
+		}),
+		fx.StartTimeout(lookupDuration("GO_APP_START_TIMEOUT", fx.DefaultTimeout)),
+		fx.StopTimeout(lookupDuration("GO_APP_STOP_TIMEOUT", fx.DefaultTimeout)),
 		fx.Provide(
-			app.plugins...,
+			p.provided...,
 		),
-		fx.Invoke(funcs...),
+		// appended after the hook of the logger plugin, so the stop report is logged before its sinks are closed
+		fx.Invoke(func(lc fx.Lifecycle) {
+			lc.Append(fx.Hook{
+				OnStop: func(ctx context.Context) error {
+					appLogger.reportStop()
+					return nil
+				},
+			})
+		}),
+		fx.Options(p.options...),
+		// invokes of modules run ahead of the ones of the application
+		fx.Module("go-app", fx.Invoke(app.checkEnv(p.constructors()))),
+		fx.Options(p.modules.options...),
+		fx.Invoke(p.invokes...),
 	)
+}
+
+type appLoggerParams struct {
+	fx.In
+
+	Logger *logger.Logger `optional:"true"`
+}
+
+// Run builds and runs the application until it is stopped, or describes it with the describe argument
+func (app *Application) Run(funcs ...interface{}) {
+	if _, args := roleFlag(os.Args[1:]); len(args) > 0 && args[0] == "describe" {
+		os.Exit(ExitCode(app.describe(os.Stdout, args[1:], funcs)))
+	}
+	if role, err := app.selectedRole(false); err == nil && role != nil {
+		logrus.Info(fmt.Sprintf("Application role %s", role.name))
+	}
+	app.fx = fx.New(app.Options(funcs...))
+	if err := app.fx.Err(); err != nil {
+		dumpGraph(err)
+	}
 	app.fx.Run()
 }
 
+// dumpGraph writes the dependency graph in DOT format when the application fails to build,
+// to the file of GO_APP_GRAPH_ON_FAILURE or stderr if it is true
+func dumpGraph(err error) {
+	target := env.Lookup("GO_APP_GRAPH_ON_FAILURE")
+	if target == "" || target == "false" {
+		return
+	}
+	graph, vErr := fx.VisualizeError(err)
+	if vErr != nil {
+		return
+	}
+	if target == "true" || target == "stderr" {
+		fmt.Fprintln(os.Stderr, graph)
+		return
+	}
+	if wErr := os.WriteFile(target, []byte(graph), 0o644); wErr != nil {
+		logrus.Error(fmt.Sprintf("Unable to write dependency graph to %s: %v", target, wErr))
+		return
+	}
+	logrus.Error(fmt.Sprintf("Dependency graph is written to %s, e.g. render by dot -Tsvg %s", target, target))
+}
+
+type checkEnvParams struct {
+	fx.In
+
+	Env *env.Env `optional:"true"`
+}
+
+// checkEnv validates the variables declared by the provided constructors ahead of other invokes,
+// and prints the effective configuration unless GO_APP_CONFIG_REPORT is false
+func (app *Application) checkEnv(constructors []interface{}) func(checkEnvParams) error {
+	return func(params checkEnvParams) error {
+		if params.Env == nil {
+			return nil
+		}
+
+		requirements := env.Requirements(constructors...)
+		if err := params.Env.Validate(requirements); err != nil {
+			return fmt.Errorf("missing required environment variables:\n%w", err)
+		}
+
+		app.configReport = params.Env.Report(requirements)
+		if params.Env.GetEnv("GO_APP_CONFIG_REPORT") != "false" {
+			for _, entry := range app.configReport {
+				logrus.Info(fmt.Sprintf("CONFIG %s=%s (%s)", entry.Name, entry.Value, entry.Origin))
+			}
+		}
+		return nil
+	}
+}
+
+// ConfigReport returns the effective configuration of variables declared by the provided plugins,
+// with secrets redacted. It is available once the application is running.
+func (app *Application) ConfigReport() []env.ConfigEntry {
+	return app.configReport
+}
+
 func (app *Application) Validate(funcs ...interface{}) error {
-	return fx.ValidateApp(
-		fx.WithLogger(func() fxevent.Logger { return AppLogger{} }),
-		fx.Provide(
-			app.plugins...,
-		),
-		fx.Invoke(funcs...),
-	)
+	return fx.ValidateApp(app.Options(funcs...))
 }
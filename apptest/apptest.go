@@ -0,0 +1,175 @@
+// Package apptest builds an Application from selected plugins for tests, with fakes, scoped env,
+// captured logs and the lifecycle bound to the test
+//
+//	func TestUserModule(t *testing.T) {
+//		a := apptest.New(t,
+//			apptest.WithModule(&user.UserModule{}),
+//			apptest.WithEnv(map[string]string{"LOG_LEVEL": "info"}),
+//			apptest.Replace(fakeRepository),
+//		)
+//		var controller *controllers.UsersController
+//		a.Start(func(c *controllers.UsersController) { controller = c })
+//
+//		controller.Create(ctx, req)
+//		if a.Logs().LastEntry().Message != "User created" { ... }
+//	}
+package apptest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	app "github.com/shoplineapp/go-app"
+	"github.com/shoplineapp/go-app/plugins/env"
+	"github.com/shoplineapp/go-app/plugins/logger"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxtest"
+)
+
+// DefaultPlugins are provided to all test applications
+var DefaultPlugins = []interface{}{env.ProvideEnv, logger.ProvideLogger}
+
+type App struct {
+	*app.Application
+
+	t       testing.TB
+	plugins []interface{}
+	logs    *test.Hook
+	fx      *fxtest.App
+}
+
+type Option func(*App)
+
+// WithPlugins provides the plugin constructors in addition to DefaultPlugins, instead of plugins.Registry
+func WithPlugins(constructors ...interface{}) Option {
+	return func(a *App) {
+		a.plugins = append(a.plugins, constructors...)
+	}
+}
+
+// WithModule adds the module and its imports
+func WithModule(module app.AppModuleInterface) Option {
+	return func(a *App) {
+		a.AddModule(module)
+	}
+}
+
+// WithEnv sets the variables for the duration of the test, tests setting env must not be parallel
+func WithEnv(values map[string]string) Option {
+	return func(a *App) {
+		for key, value := range values {
+			a.t.Setenv(key, value)
+		}
+	}
+}
+
+// Replace replaces provided values with fakes, e.g. Replace(fakeRepository) or
+// Replace(fx.Annotate(fake, fx.As(new(Repository))))
+func Replace(values ...interface{}) Option {
+	return WithOptions(fx.Replace(values...))
+}
+
+// Decorate wraps provided values, e.g. a spy of the provided client
+func Decorate(decorators ...interface{}) Option {
+	return WithOptions(fx.Decorate(decorators...))
+}
+
+// WithOptions adds fx options to the application
+func WithOptions(options ...fx.Option) Option {
+	return func(a *App) {
+		a.AddOptions(options...)
+	}
+}
+
+// New builds the test application, which is isolated from .env files, mounted secrets and the global plugins.Registry
+func New(t testing.TB, opts ...Option) *App {
+	t.Helper()
+
+	a := &App{
+		Application: app.NewApplication(),
+		t:           t,
+		plugins:     append([]interface{}{}, DefaultPlugins...),
+		logs:        &test.Hook{},
+	}
+	t.Setenv("ENVIRONMENT", "test")
+	t.Setenv("PROJECT_ROOT", t.TempDir())
+	t.Setenv("SECRETS_DIR", t.TempDir())
+	t.Setenv("GO_APP_CONFIG_REPORT", "false")
+
+	for _, opt := range opts {
+		opt(a)
+	}
+	a.SetPlugins(a.plugins...)
+
+	// the fx events and plugins logging with the standard logger
+	std := logrus.StandardLogger()
+	hooks := make(logrus.LevelHooks)
+	for level, levelHooks := range std.Hooks {
+		hooks[level] = append([]logrus.Hook{}, levelHooks...)
+	}
+	std.AddHook(a.logs)
+	t.Cleanup(func() { std.ReplaceHooks(hooks) })
+
+	if provides(a.plugins, logger.ProvideLogger) {
+		a.AddOptions(fx.Decorate(func(l *logger.Logger) *logger.Logger {
+			l.AddHook(a.logs)
+			l.SetOutput(testWriter{t: t})
+			return l
+		}))
+	}
+
+	return a
+}
+
+func provides(constructors []interface{}, constructor interface{}) bool {
+	target := reflect.ValueOf(constructor).Pointer()
+	for _, c := range constructors {
+		if v := reflect.ValueOf(c); v.Kind() == reflect.Func && v.Pointer() == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Start builds and starts the application with funcs invoked, the application is stopped on cleanup of the test
+func (a *App) Start(funcs ...interface{}) {
+	a.t.Helper()
+	a.fx = fxtest.New(a.t, a.Options(funcs...))
+	a.fx.RequireStart()
+	a.t.Cleanup(a.fx.RequireStop)
+}
+
+// Populate starts the application and fills the targets with provided values, e.g. Populate(&controller)
+func (a *App) Populate(targets ...interface{}) {
+	a.t.Helper()
+	a.AddOptions(fx.Populate(targets...))
+	a.Start()
+}
+
+// Logs returns the entries logged by the logger plugin and the standard logger
+func (a *App) Logs() *test.Hook {
+	return a.logs
+}
+
+// Logged returns whether an entry with the message is logged at the level
+func (a *App) Logged(level logrus.Level, message string) bool {
+	for _, entry := range a.logs.AllEntries() {
+		if entry.Level == level && strings.Contains(entry.Message, message) {
+			return true
+		}
+	}
+	return false
+}
+
+// testWriter writes the logger output to the test log, which is shown for failed or verbose tests
+type testWriter struct {
+	t testing.TB
+}
+
+func (w testWriter) Write(p []byte) (int, error) {
+	w.t.Log(strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
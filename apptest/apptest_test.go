@@ -0,0 +1,72 @@
+package apptest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shoplineapp/go-app/plugins/env"
+	"github.com/shoplineapp/go-app/plugins/logger"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/fx"
+)
+
+type greeter interface {
+	Greet() string
+}
+
+type realGreeter struct{}
+
+func (realGreeter) Greet() string { return "real" }
+
+type fakeGreeter struct{}
+
+func (fakeGreeter) Greet() string { return "fake" }
+
+func newGreeter() greeter { return realGreeter{} }
+
+type greetingService struct {
+	greeter greeter
+	logger  *logger.Logger
+	stopped bool
+}
+
+func newGreetingService(lc fx.Lifecycle, g greeter, l *logger.Logger) *greetingService {
+	s := &greetingService{greeter: g, logger: l}
+	lc.Append(fx.Hook{OnStop: func(context.Context) error {
+		s.stopped = true
+		return nil
+	}})
+	return s
+}
+
+func (s *greetingService) Greet() string {
+	greeting := s.greeter.Greet()
+	s.logger.WithField("greeting", greeting).Info("Greeted")
+	return greeting
+}
+
+func TestApp(t *testing.T) {
+	var service *greetingService
+	var e *env.Env
+	t.Run("replaces dependencies", func(t *testing.T) {
+		a := New(t,
+			WithPlugins(newGreeter, newGreetingService),
+			WithEnv(map[string]string{"GREETING_NAME": "apptest"}),
+			Replace(fx.Annotate(fakeGreeter{}, fx.As(new(greeter)))),
+		)
+		a.Populate(&service, &e)
+
+		if service.Greet() != "fake" {
+			t.Fatal("greeter is not replaced")
+		}
+		if e.GetEnv("GREETING_NAME") != "apptest" {
+			t.Fatal("env is not set")
+		}
+		if !a.Logged(logrus.InfoLevel, "Greeted") || a.Logs().LastEntry().Data["greeting"] != "fake" {
+			t.Fatal("logs are not captured")
+		}
+	})
+	if !service.stopped {
+		t.Fatal("application is not stopped on cleanup")
+	}
+}
@@ -0,0 +1,140 @@
+// go-app scaffolds services of go-app
+//
+//	go install github.com/shoplineapp/go-app/cmd/go-app@latest
+//	go-app new github.com/acme/orders
+//	cd orders && go mod tidy && make run
+//	go-app add module payments
+//	go-app add controller payments refunds
+//	go-app add consumer payments order-created
+//	go-app add handler payments invoice-paid
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const usage = `Usage:
+  go-app new <module path> [-dir dir] [-transport grpc|kitex] [-module name] [-replace path]
+  go-app add module <name>
+  go-app add controller <module> <name> [-kitex]
+  go-app add consumer <module> <name>
+  go-app add handler <module> <name>
+
+Commands of add run in the directory of the service, or -dir.
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, errUsage) {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+var errUsage = errors.New("invalid arguments")
+
+func run(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch args[0] {
+	case "new":
+		return runNew(args[1:], out)
+	case "add":
+		return runAdd(args[1:], out)
+	case "help", "-h", "--help":
+		fmt.Fprint(out, usage)
+		return nil
+	}
+	return errUsage
+}
+
+func runNew(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("new", flag.ContinueOnError)
+	dir := flags.String("dir", "", "directory of the service, the last element of the module path by default")
+	opts := NewProjectOptions{}
+	flags.StringVar(&opts.Transport, "transport", "grpc", "server of the service, grpc or kitex")
+	flags.StringVar(&opts.Module, "module", "", "first module of the service, named after the service by default")
+	flags.StringVar(&opts.Replace, "replace", "", "local path of go-app to replace the dependency")
+	goModule, err := parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	if *dir == "" {
+		*dir = filepath.Base(goModule[0])
+	}
+	if _, err := NewProject(*dir, goModule[0], opts); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Service %s is created in %s, run go mod tidy and make run in it\n", goModule[0], *dir)
+	return nil
+}
+
+func runAdd(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	kind := args[0]
+	flags := flag.NewFlagSet("add "+kind, flag.ContinueOnError)
+	dir := flags.String("dir", ".", "directory of the service")
+	kitex := flags.Bool("kitex", false, "kitex controller, by default if the service is built with kitex")
+
+	positional := 2
+	if kind == "module" {
+		positional = 1
+	}
+	names, err := parse(flags, args[1:], positional)
+	if err != nil {
+		return err
+	}
+	p, err := OpenProject(*dir)
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case "module":
+		err = p.AddModule(names[0])
+	case "controller":
+		err = p.AddController(names[0], names[1], *kitex || contains(p.Tags(), "kitex"))
+	case "consumer":
+		err = p.AddConsumer(names[0], names[1])
+	case "handler":
+		err = p.AddHandler(names[0], names[1])
+	default:
+		return errUsage
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Added %s %s\n", kind, names[len(names)-1])
+	return nil
+}
+
+// parse parses flags before or after the positional arguments
+func parse(flags *flag.FlagSet, args []string, positional int) ([]string, error) {
+	flags.SetOutput(io.Discard)
+	values := []string{}
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		values = append(values, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	if len(values) != positional {
+		return nil, errUsage
+	}
+	return values, nil
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/stoewer/go-strcase"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var templates = template.Must(template.ParseFS(templateFS, "templates/*.tmpl"))
+
+// Transports are the build tags of the servers a service is scaffolded with
+var Transports = map[string][]string{
+	"grpc":  {"grpc", "newrelic", "otel"},
+	"kitex": {"kitex", "newrelic"},
+}
+
+// markers are the lines generated code is inserted above
+const (
+	importsMarker     = "// go-app:imports"
+	modulesMarker     = "// go-app:modules"
+	controllersMarker = "// go-app:controllers"
+	invokesMarker     = "// go-app:invokes"
+)
+
+// Project is a service scaffolded in Dir
+type Project struct {
+	Dir string
+	// GoModule is the module path of go.mod
+	GoModule string
+}
+
+// NewProjectOptions are the options of a new service
+type NewProjectOptions struct {
+	Transport string
+	// Module is the first module of the service, named after the service by default
+	Module string
+	// Replace is the local path of go-app, for developing go-app itself
+	Replace string
+}
+
+// component is the template data of generated files
+type component struct {
+	GoModule string
+	Package  string
+	// Name is the name in upper camel case, e.g. OrderCreated
+	Name  string
+	Type  string
+	Label string
+	Tags  string
+
+	Replace string
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+func newComponent(name string, suffix string) (component, error) {
+	if !identifier.MatchString(name) {
+		return component{}, fmt.Errorf("invalid name %q, letters, digits, - and _ are allowed", name)
+	}
+	camel := strcase.UpperCamelCase(name)
+	return component{Name: camel, Type: camel + suffix, Label: strcase.KebabCase(name)}, nil
+}
+
+// NewProject scaffolds a service of the go module in dir, which must not exist or be empty
+func NewProject(dir string, goModule string, opts NewProjectOptions) (*Project, error) {
+	if goModule == "" {
+		return nil, errors.New("module path is required")
+	}
+	if opts.Transport == "" {
+		opts.Transport = "grpc"
+	}
+	tags, ok := Transports[opts.Transport]
+	if !ok {
+		return nil, fmt.Errorf("transport %s is not one of grpc, kitex", opts.Transport)
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("%s is not empty", dir)
+	}
+	if opts.Module == "" {
+		opts.Module = strcase.SnakeCase(filepath.Base(goModule))
+	}
+
+	p := &Project{Dir: dir, GoModule: goModule}
+	data := component{GoModule: goModule, Tags: strings.Join(tags, ","), Replace: opts.Replace}
+	files := map[string]string{
+		"go.mod":          "go.mod.tmpl",
+		"cmd/app/main.go": "main.go.tmpl",
+		"Makefile":        "Makefile.tmpl",
+		".env":            "env.tmpl",
+		".env.test":       "env.test.tmpl",
+		".gitignore":      "gitignore.tmpl",
+	}
+	for path, name := range files {
+		if err := p.render(path, name, data); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.AddModule(opts.Module); err != nil {
+		return nil, err
+	}
+	if err := p.AddController(opts.Module, opts.Module, opts.Transport == "kitex"); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// OpenProject opens the service scaffolded in dir
+func OpenProject(dir string) (*Project, error) {
+	content, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a go module: %w", dir, err)
+	}
+	match := regexp.MustCompile(`(?m)^module\s+(\S+)`).FindSubmatch(content)
+	if match == nil {
+		return nil, fmt.Errorf("module path is not found in %s/go.mod", dir)
+	}
+	return &Project{Dir: dir, GoModule: string(match[1])}, nil
+}
+
+// AddModule adds internal/<name>/module.go and adds the module to the application
+func (p *Project) AddModule(name string) error {
+	c, err := newComponent(name, "Module")
+	if err != nil {
+		return err
+	}
+	c.Package = strcase.SnakeCase(name)
+	path := filepath.Join("internal", c.Package, "module.go")
+	if _, err := os.Stat(filepath.Join(p.Dir, path)); err == nil {
+		return fmt.Errorf("module %s already exists", name)
+	}
+	if err := p.render(path, "module.go.tmpl", c); err != nil {
+		return err
+	}
+	return p.insert("cmd/app/main.go", map[string]string{
+		importsMarker: fmt.Sprintf("%q", p.GoModule+"/internal/"+c.Package),
+		modulesMarker: fmt.Sprintf("app.AddModule(&%s.%s{})", c.Package, c.Type),
+	})
+}
+
+// AddController adds a gRPC controller, or a kitex one, to the module
+func (p *Project) AddController(module string, name string, kitex bool) error {
+	tmpl, tags := "grpc_controller.go.tmpl", Transports["grpc"]
+	if kitex {
+		tmpl, tags = "kitex_controller.go.tmpl", Transports["kitex"]
+	}
+	return p.addComponent(module, name, "Controller", tmpl, tags, nil)
+}
+
+// AddConsumer adds a Pulsar consumer implementing pulsar.PulsarConsumerInterface to the module
+func (p *Project) AddConsumer(module string, name string) error {
+	return p.addComponent(module, name, "Consumer", "pulsar_consumer.go.tmpl", []string{"pulsar"}, map[string]string{"PULSAR_URL": "pulsar://localhost:6650"})
+}
+
+// AddHandler adds an SQS handler implementing sqs_worker.EventHandlerInterface to the module
+func (p *Project) AddHandler(module string, name string) error {
+	return p.addComponent(module, name, "Handler", "sqs_handler.go.tmpl", []string{"sqs", "sqs_worker"}, map[string]string{"AWS_REGION": "ap-southeast-1"})
+}
+
+// addComponent renders the component into the module, registers its constructor and invoke, and adds
+// the build tags and env variables it needs
+func (p *Project) addComponent(module string, name string, suffix string, tmpl string, tags []string, env map[string]string) error {
+	c, err := newComponent(name, suffix)
+	if err != nil {
+		return err
+	}
+	c.Package = strcase.SnakeCase(module)
+	modulePath := filepath.Join("internal", c.Package, "module.go")
+	if _, err := os.Stat(filepath.Join(p.Dir, modulePath)); err != nil {
+		return fmt.Errorf("module %s is not found, add it by go-app add module %s", module, module)
+	}
+	path := filepath.Join("internal", c.Package, fmt.Sprintf("%s_%s.go", strcase.SnakeCase(name), strings.ToLower(suffix)))
+	if _, err := os.Stat(filepath.Join(p.Dir, path)); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+	if err := p.render(path, tmpl, c); err != nil {
+		return err
+	}
+	if err := p.insert(modulePath, map[string]string{
+		controllersMarker: fmt.Sprintf("New%s,", c.Type),
+		invokesMarker:     fmt.Sprintf("Register%s,", c.Type),
+	}); err != nil {
+		return err
+	}
+	if err := p.addTags(tags); err != nil {
+		return err
+	}
+	return p.addEnv(env)
+}
+
+// render writes the template to the path in the project, formatted if it is go code
+func (p *Project) render(path string, name string, data component) error {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return err
+	}
+	content := buf.Bytes()
+	if filepath.Ext(path) == ".go" {
+		formatted, err := format.Source(content)
+		if err != nil {
+			return fmt.Errorf("unable to format %s: %w", path, err)
+		}
+		content = formatted
+	}
+	target := filepath.Join(p.Dir, path)
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(target, content, 0o644)
+}
+
+// insert adds the lines above their markers of the go file, unless the lines exist
+func (p *Project) insert(path string, lines map[string]string) error {
+	target := filepath.Join(p.Dir, path)
+	content, err := os.ReadFile(target)
+	if err != nil {
+		return err
+	}
+	source := string(content)
+	for marker, line := range lines {
+		if strings.Contains(source, line) {
+			continue
+		}
+		index := strings.Index(source, marker)
+		if index < 0 {
+			return fmt.Errorf("marker %s is not found in %s", marker, path)
+		}
+		// indent of the marker
+		start := strings.LastIndex(source[:index], "\n") + 1
+		source = source[:start] + source[start:index] + line + "\n" + source[start:]
+	}
+	formatted, err := format.Source([]byte(source))
+	if err != nil {
+		return fmt.Errorf("unable to format %s: %w", path, err)
+	}
+	return os.WriteFile(target, formatted, 0o644)
+}
+
+var tagsLine = regexp.MustCompile(`(?m)^TAGS \?= (.*)$`)
+
+// addTags adds the build tags to TAGS of the Makefile
+func (p *Project) addTags(tags []string) error {
+	target := filepath.Join(p.Dir, "Makefile")
+	content, err := os.ReadFile(target)
+	if err != nil {
+		return err
+	}
+	match := tagsLine.FindSubmatch(content)
+	if match == nil {
+		return errors.New("TAGS is not found in the Makefile")
+	}
+	current := strings.Split(string(match[1]), ",")
+	for _, tag := range tags {
+		if !contains(current, tag) {
+			current = append(current, tag)
+		}
+	}
+	content = tagsLine.ReplaceAll(content, []byte("TAGS ?= "+strings.Join(current, ",")))
+	return os.WriteFile(target, content, 0o644)
+}
+
+// Tags returns the build tags of the project
+func (p *Project) Tags() []string {
+	content, err := os.ReadFile(filepath.Join(p.Dir, "Makefile"))
+	if err != nil {
+		return nil
+	}
+	if match := tagsLine.FindSubmatch(content); match != nil {
+		return strings.Split(string(match[1]), ",")
+	}
+	return nil
+}
+
+// addEnv appends the variables missing in .env
+func (p *Project) addEnv(values map[string]string) error {
+	target := filepath.Join(p.Dir, ".env")
+	content, err := os.ReadFile(target)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for key, value := range values {
+		if regexp.MustCompile(`(?m)^` + key + `=`).Match(content) {
+			continue
+		}
+		content = append(content, []byte(fmt.Sprintf("%s=%s\n", key, value))...)
+	}
+	return os.WriteFile(target, content, 0o644)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// build compiles the generated service against this checkout of go-app from the module cache
+func build(t *testing.T, dir string, tags []string) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("compiling the generated service is skipped in short mode")
+	}
+	sum, err := os.ReadFile("../../go.sum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.sum"), sum, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"build", "./..."}, {"vet", "./..."}} {
+		cmd := exec.Command("go", append(args[:1], append([]string{"-mod=mod", "-tags", strings.Join(tags, ",")}, args[1:]...)...)...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOPROXY=off", "GOFLAGS=-mod=mod")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("go %s of the generated service failed: %v\n%s", args[0], err, out)
+		}
+	}
+}
+
+func TestScaffold(t *testing.T) {
+	root, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(t.TempDir(), "orders")
+	if err := run([]string{"new", "github.com/acme/orders", "-dir", dir, "-replace", root}, os.Stdout); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"add", "module", "payments", "-dir", dir},
+		{"add", "controller", "payments", "refunds", "-dir", dir},
+		{"add", "consumer", "payments", "order-created", "-dir", dir},
+		{"add", "handler", "orders", "invoice_paid", "-dir", dir},
+	} {
+		if err := run(args, os.Stdout); err != nil {
+			t.Fatalf("%v failed: %v", args, err)
+		}
+	}
+
+	if err := run([]string{"add", "controller", "shipping", "labels", "-dir", dir}, os.Stdout); err == nil {
+		t.Fatal("controller of a missing module is added")
+	}
+	if err := run([]string{"add", "consumer", "payments", "order-created", "-dir", dir}, os.Stdout); err == nil {
+		t.Fatal("existing consumer is overwritten")
+	}
+
+	p, err := OpenProject(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags := strings.Join(p.Tags(), ","); tags != "grpc,newrelic,otel,pulsar,sqs,sqs_worker" {
+		t.Fatalf("tags of the Makefile are %s", tags)
+	}
+	module, _ := os.ReadFile(filepath.Join(dir, "internal/payments/module.go"))
+	for _, line := range []string{"NewRefundsController,", "RegisterRefundsController,", "NewOrderCreatedConsumer,", "RegisterOrderCreatedConsumer,"} {
+		if !strings.Contains(string(module), line) {
+			t.Fatalf("%s is not registered in the module:\n%s", line, module)
+		}
+	}
+	build(t, dir, p.Tags())
+}
+
+// TestScaffoldKitex only parses the generated service, kitex is built with the kitex tool
+func TestScaffoldKitex(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "checkout")
+	if _, err := NewProject(dir, "github.com/acme/checkout", NewProjectOptions{Transport: "kitex"}); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := OpenProject(dir)
+	if err := p.AddController("checkout", "orders", contains(p.Tags(), "kitex")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "internal/checkout/orders_controller.go")); err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	for _, path := range []string{"cmd/app/main.go", "internal/checkout/module.go", "internal/checkout/orders_controller.go"} {
+		if _, err := parser.ParseFile(fset, filepath.Join(dir, path), nil, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if controller, _ := os.ReadFile(filepath.Join(dir, "internal/checkout/orders_controller.go")); !strings.Contains(string(controller), "DefaultKitexServerWithNewrelic") {
+		t.Fatalf("kitex controller is not generated:\n%s", controller)
+	}
+}
@@ -0,0 +1,273 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+	"go.uber.org/fx"
+)
+
+// Command is a one-off task, e.g. a migration or backfill, run with the dependency graph of the application.
+// Run is invoked with dependencies injected, context.Context is cancelled on SIGINT or SIGTERM and Args are
+// the arguments after the command name. Lifecycle hooks are not started, so servers and consumers are not run,
+// but hooks with OnStop only, e.g. flushing the logger or disconnecting stores, are stopped after the command.
+//
+//	app.AddCommand(go_app.Command{
+//		Name:        "backfill",
+//		Description: "Backfill the users",
+//		Run: func(ctx context.Context, args go_app.Args, repo *user.Repository) error {
+//			return repo.Backfill(ctx, args...)
+//		},
+//	})
+type Command struct {
+	Name        string
+	Description string
+	// Run is a function with injected dependencies, returning nothing or an error
+	Run interface{}
+	// Commands are subcommands, e.g. migrate up
+	Commands []Command
+	// direct runs the command without the dependency graph
+	direct func(ctx context.Context, args Args) error
+}
+
+// Args are the arguments of the command
+type Args []string
+
+// ModuleCommander contributes commands of the module
+type ModuleCommander interface {
+	Commands() []Command
+}
+
+// ExitError exits the command with the code
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// ExitCode returns the exit code of the command error, 130 if it is interrupted
+func ExitCode(err error) int {
+	var coder interface{ ExitCode() int }
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &coder):
+		return coder.ExitCode()
+	case errors.Is(err, context.Canceled):
+		return 130
+	default:
+		return 1
+	}
+}
+
+var errUsage = &ExitError{Code: 2, Err: errors.New("unknown command")}
+
+// AddCommand adds commands to the application
+func (app *Application) AddCommand(commands ...Command) {
+	app.commands = append(app.commands, commands...)
+}
+
+// Commands returns the commands of the application and its modules, including the ones of the selected role
+func (app *Application) Commands() []Command {
+	commands := append([]Command{}, app.commands...)
+	if command, _, _ := findCommand(commands, []string{"describe"}); command == nil {
+		commands = append(commands, app.describeCommand())
+	}
+	modules := append([]AppModuleInterface{}, app.modules...)
+	if role, _ := app.selectedRole(true); role != nil {
+		modules = append(modules, role.modules...)
+	}
+	if set, err := buildModules(modules); err == nil {
+		for _, module := range set.modules {
+			if commander, ok := module.(ModuleCommander); ok {
+				commands = append(commands, commander.Commands()...)
+			}
+		}
+	}
+	return commands
+}
+
+// RunCommand runs the command of args, os.Args by default, and exits with the code of its error
+func (app *Application) RunCommand(args ...string) {
+	if len(args) == 0 {
+		_, args = roleFlag(os.Args[1:])
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := app.ExecuteCommand(ctx, args)
+	if errors.Is(err, errUsage) {
+		app.usage(os.Stderr, args)
+	} else if err != nil {
+		logrus.Error(fmt.Sprintf("Command failed: %v", err))
+	}
+	stop()
+	os.Exit(ExitCode(err))
+}
+
+// ExecuteCommand builds the application and runs the command of args without starting it, then stops the hooks
+// with OnStop only within GO_APP_STOP_TIMEOUT
+func (app *Application) ExecuteCommand(ctx context.Context, args []string) error {
+	command, path, rest := findCommand(app.Commands(), args)
+	if command != nil && command.direct != nil {
+		return command.direct(ctx, rest)
+	}
+	if command == nil || command.Run == nil {
+		if len(args) == 0 {
+			app.usage(os.Stdout, nil)
+			return nil
+		}
+		if args[0] == "help" {
+			app.usage(os.Stdout, args[1:])
+			return nil
+		}
+		return errUsage
+	}
+
+	role, err := app.selectedRole(true)
+	if err != nil {
+		return err
+	}
+	run, result := commandInvoke(command.Run)
+	if run == nil {
+		return fmt.Errorf("run of command %s is not a function", strings.Join(path, " "))
+	}
+
+	logrus.Info(fmt.Sprintf("COMMAND %s", strings.Join(path, " ")))
+	lifecycle := &commandLifecycle{}
+	fxApp := fx.New(
+		app.build(role, nil),
+		fx.Decorate(func(lc fx.Lifecycle) fx.Lifecycle { lifecycle.Lifecycle = lc; return lifecycle }),
+		fx.Supply(Args(rest)),
+		fx.Provide(func() context.Context { return ctx }),
+		fx.Invoke(run),
+	)
+	stopCtx, cancel := context.WithTimeout(context.Background(), lookupDuration("GO_APP_STOP_TIMEOUT", fx.DefaultTimeout))
+	defer cancel()
+	stopErr := lifecycle.stop(stopCtx)
+	if err := fxApp.Err(); err != nil {
+		dumpGraph(err)
+		return err
+	}
+	if stopErr != nil {
+		logrus.Error(fmt.Sprintf("Command stop failed: %v", stopErr))
+	}
+	return *result
+}
+
+// commandLifecycle keeps the hooks with OnStop only, which release resources acquired by constructors,
+// to be stopped after the command while hooks starting servers or consumers are ignored
+type commandLifecycle struct {
+	fx.Lifecycle
+	mu    sync.Mutex
+	stops []func(context.Context) error
+}
+
+func (l *commandLifecycle) Append(hook fx.Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if hook.OnStart == nil && hook.OnStop != nil {
+		l.stops = append(l.stops, hook.OnStop)
+	}
+}
+
+// stop runs the kept hooks in reverse order, like fx.App.Stop
+func (l *commandLifecycle) stop(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var errs []error
+	for i := len(l.stops) - 1; i >= 0; i-- {
+		if err := l.stops[i](ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	l.stops = nil
+	return errors.Join(errs...)
+}
+
+// findCommand returns the deepest command matching args, its path and remaining args
+func findCommand(commands []Command, args []string) (*Command, []string, []string) {
+	var found *Command
+	path := []string{}
+	for len(args) > 0 {
+		var next *Command
+		for i := range commands {
+			if commands[i].Name == args[0] {
+				next = &commands[i]
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		found, commands, path, args = next, next.Commands, append(path, next.Name), args[1:]
+	}
+	return found, path, args
+}
+
+// commandInvoke wraps run as an invoke which keeps the returned error instead of failing the application
+func commandInvoke(run interface{}) (interface{}, *error) {
+	var result error
+	fn := reflect.ValueOf(run)
+	if fn.Kind() != reflect.Func {
+		return nil, &result
+	}
+
+	in := make([]reflect.Type, fn.Type().NumIn())
+	for i := range in {
+		in[i] = fn.Type().In(i)
+	}
+	wrapper := reflect.MakeFunc(reflect.FuncOf(in, nil, fn.Type().IsVariadic()), func(args []reflect.Value) []reflect.Value {
+		var out []reflect.Value
+		if fn.Type().IsVariadic() {
+			out = fn.CallSlice(args)
+		} else {
+			out = fn.Call(args)
+		}
+		if len(out) > 0 {
+			if err, ok := out[len(out)-1].Interface().(error); ok {
+				result = err
+			}
+		}
+		return nil
+	})
+	return wrapper.Interface(), &result
+}
+
+func (app *Application) usage(w io.Writer, args []string) {
+	commands := app.Commands()
+	command, path, _ := findCommand(commands, args)
+	if command != nil {
+		commands = append([]Command{}, command.Commands...)
+	}
+
+	fmt.Fprintf(w, "Usage: %s %s<command> [args]\n\nCommands:\n", os.Args[0], strings.Join(append(path, ""), " "))
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Name < commands[j].Name })
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-20s %s\n", c.Name, c.Description)
+	}
+}
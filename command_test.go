@@ -0,0 +1,74 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	app "github.com/shoplineapp/go-app"
+	"github.com/shoplineapp/go-app/apptest"
+	"go.uber.org/fx"
+)
+
+type migrationModule struct {
+	storageModule
+	migrated *[]string
+}
+
+func (m *migrationModule) Commands() []app.Command {
+	return []app.Command{{
+		Name: "migrate",
+		Commands: []app.Command{{
+			Name: "up",
+			Run: func(ctx context.Context, args app.Args, r *repository) error {
+				*m.migrated = append(*m.migrated, r.store.dsn+" "+strings.Join(args, ","))
+				return nil
+			},
+		}},
+	}}
+}
+
+func TestCommand(t *testing.T) {
+	var migrated, events []string
+	a := apptest.New(t, apptest.WithModule(&migrationModule{storageModule: storageModule{events: &events}, migrated: &migrated}))
+	a.AddCommand(app.Command{
+		Name: "cleanup",
+		Run: func(lc fx.Lifecycle) {
+			lc.Append(fx.Hook{OnStop: func(ctx context.Context) error {
+				events = append(events, "cleaned up")
+				return nil
+			}})
+		},
+	}, app.Command{
+		Name: "fail",
+		Run: func(ctx context.Context) error {
+			return &app.ExitError{Code: 3, Err: errors.New("failed")}
+		},
+	})
+
+	if err := a.ExecuteCommand(context.Background(), []string{"migrate", "up", "v1", "v2"}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(migrated, ";") != "memory v1,v2" {
+		t.Fatalf("subcommand is not run with dependencies and args but %v", migrated)
+	}
+	if len(events) > 0 {
+		t.Fatalf("lifecycle is started by command: %v", events)
+	}
+
+	if err := a.ExecuteCommand(context.Background(), []string{"cleanup"}); err != nil || strings.Join(events, ";") != "cleaned up" {
+		t.Fatalf("hooks with OnStop only are not stopped after command: %v, %v", events, err)
+	}
+
+	err := a.ExecuteCommand(context.Background(), []string{"fail"})
+	if app.ExitCode(err) != 3 {
+		t.Fatalf("exit code of command error is not used but %v", err)
+	}
+	if err := a.ExecuteCommand(context.Background(), []string{"unknown"}); app.ExitCode(err) != 2 {
+		t.Fatalf("unknown command is not a usage error but %v", err)
+	}
+	if app.ExitCode(context.Canceled) != 130 {
+		t.Fatal("interrupted command is not exited with 130")
+	}
+}
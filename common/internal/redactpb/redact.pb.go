@@ -0,0 +1,163 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: common/internal/redactpb/redact.proto
+
+package redactpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Account is a generated message to test redaction by field options
+type Account struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Password      string                 `protobuf:"bytes,1,opt,name=password,proto3" json:"password,omitempty"`
+	Phone         string                 `protobuf:"bytes,2,opt,name=phone,proto3" json:"phone,omitempty"`
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Account) Reset() {
+	*x = Account{}
+	mi := &file_common_internal_redactpb_redact_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Account) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Account) ProtoMessage() {}
+
+func (x *Account) ProtoReflect() protoreflect.Message {
+	mi := &file_common_internal_redactpb_redact_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Account.ProtoReflect.Descriptor instead.
+func (*Account) Descriptor() ([]byte, []int) {
+	return file_common_internal_redactpb_redact_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Account) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *Account) GetPhone() string {
+	if x != nil {
+		return x.Phone
+	}
+	return ""
+}
+
+func (x *Account) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+var file_common_internal_redactpb_redact_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.FieldOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         50001,
+		Name:          "go_app.common.redactpb.redact",
+		Tag:           "bytes,50001,opt,name=redact",
+		Filename:      "common/internal/redactpb/redact.proto",
+	},
+}
+
+// Extension fields to descriptorpb.FieldOptions.
+var (
+	// optional string redact = 50001;
+	E_Redact = &file_common_internal_redactpb_redact_proto_extTypes[0]
+)
+
+var File_common_internal_redactpb_redact_proto protoreflect.FileDescriptor
+
+const file_common_internal_redactpb_redact_proto_rawDesc = "" +
+	"\n" +
+	"%common/internal/redactpb/redact.proto\x12\x16go_app.common.redactpb\x1a google/protobuf/descriptor.proto\"^\n" +
+	"\aAccount\x12\x1f\n" +
+	"\bpassword\x18\x01 \x01(\tB\x03\x80\x01\x01R\bpassword\x12\x1e\n" +
+	"\x05phone\x18\x02 \x01(\tB\b\x8a\xb5\x18\x04hashR\x05phone\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name:7\n" +
+	"\x06redact\x12\x1d.google.protobuf.FieldOptions\x18ц\x03 \x01(\tR\x06redactB8Z6github.com/shoplineapp/go-app/common/internal/redactpbb\x06proto3"
+
+var (
+	file_common_internal_redactpb_redact_proto_rawDescOnce sync.Once
+	file_common_internal_redactpb_redact_proto_rawDescData []byte
+)
+
+func file_common_internal_redactpb_redact_proto_rawDescGZIP() []byte {
+	file_common_internal_redactpb_redact_proto_rawDescOnce.Do(func() {
+		file_common_internal_redactpb_redact_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_common_internal_redactpb_redact_proto_rawDesc), len(file_common_internal_redactpb_redact_proto_rawDesc)))
+	})
+	return file_common_internal_redactpb_redact_proto_rawDescData
+}
+
+var file_common_internal_redactpb_redact_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_common_internal_redactpb_redact_proto_goTypes = []any{
+	(*Account)(nil),                   // 0: go_app.common.redactpb.Account
+	(*descriptorpb.FieldOptions)(nil), // 1: google.protobuf.FieldOptions
+}
+var file_common_internal_redactpb_redact_proto_depIdxs = []int32{
+	1, // 0: go_app.common.redactpb.redact:extendee -> google.protobuf.FieldOptions
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	0, // [0:1] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_common_internal_redactpb_redact_proto_init() }
+func file_common_internal_redactpb_redact_proto_init() {
+	if File_common_internal_redactpb_redact_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_common_internal_redactpb_redact_proto_rawDesc), len(file_common_internal_redactpb_redact_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 1,
+			NumServices:   0,
+		},
+		GoTypes:           file_common_internal_redactpb_redact_proto_goTypes,
+		DependencyIndexes: file_common_internal_redactpb_redact_proto_depIdxs,
+		MessageInfos:      file_common_internal_redactpb_redact_proto_msgTypes,
+		ExtensionInfos:    file_common_internal_redactpb_redact_proto_extTypes,
+	}.Build()
+	File_common_internal_redactpb_redact_proto = out.File
+	file_common_internal_redactpb_redact_proto_goTypes = nil
+	file_common_internal_redactpb_redact_proto_depIdxs = nil
+}
@@ -2,9 +2,12 @@ package common
 
 import (
 	"fmt"
+	"maps"
 	"math"
 	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/stoewer/go-strcase"
 	"golang.org/x/exp/utf8string"
@@ -31,10 +34,43 @@ func init() {
 	DefaultRedactor.AddFilters(DefaultFilters...)
 }
 
+// Redactor redacts values by filters, filters must be added with AddFilters
+// for the lookups and cached type plans to be refreshed
 type Redactor struct {
 	StructFilters       []*Filter
 	StructFieldFilters  []*Filter
 	GeneralFieldFilters []*Filter
+	PathFilters         []*Filter
+
+	// guards changes of filters and the scanner, Redact reads the compiled state without locking
+	mu      sync.Mutex
+	scanner *Scanner
+	state   atomic.Pointer[redactorState]
+
+	// filters of the parent apply ahead of the own ones, see WithScanner
+	parent *Redactor
+}
+
+// redactorState is compiled from the filters and the scanner, and replaced as a whole on changes
+// so concurrent redactions see either the old or the new one
+type redactorState struct {
+	// the state of the parent compiled from
+	base *redactorState
+
+	scanner            *Scanner
+	pathPatterns       []*pathPattern
+	structLookup       map[reflect.Type]*Filter
+	structFieldLookup  map[structFieldKey]*Filter
+	structFieldTypes   map[reflect.Type]bool
+	generalFieldLookup map[string]*Filter
+
+	// reflect.Type => *typePlan
+	plans sync.Map
+}
+
+type structFieldKey struct {
+	structType reflect.Type
+	field      string
 }
 
 type Mode func(reflect.Value) any
@@ -42,6 +78,7 @@ type Mode func(reflect.Value) any
 type Filter struct {
 	Struct     reflect.Type
 	Field      string
+	Path       string
 	RedactFunc Mode
 }
 
@@ -65,10 +102,40 @@ func fieldKey(field reflect.StructField) string {
 	return field.Name
 }
 
+var (
+	modes = map[string]Mode{
+		"full":    FullRedact,
+		"partial": PartialRedact,
+		"hash":    HashRedact,
+	}
+	modesMu sync.RWMutex
+)
+
+// RegisterMode makes the mode available by name, e.g. in `redact:"name"` struct tags
+func RegisterMode(name string, mode Mode) {
+	modesMu.Lock()
+	defer modesMu.Unlock()
+	modes[name] = mode
+}
+
+// ModeByName returns the mode registered with the name
+func ModeByName(name string) (Mode, bool) {
+	modesMu.RLock()
+	defer modesMu.RUnlock()
+	mode, ok := modes[name]
+	return mode, ok
+}
+
 func FullRedact(T reflect.Value) any {
 	return "<REDACTED>"
 }
 
+// HashRedact replaces the value with the keyed digest of HMACRedact, so equal values are still correlatable.
+// Values are fully redacted without a key, as unkeyed digests of low entropy values are reversible.
+func HashRedact(rT reflect.Value) any {
+	return HMACRedact(rT)
+}
+
 func PartialRedact(rT reflect.Value) any {
 	if !rT.IsValid() {
 		return nil
@@ -136,6 +203,12 @@ func NewFilter(data any, fieldName string, mode Mode) *Filter {
 	return f
 }
 
+// NewPathFilter redacts the values at the path, which might contain wildcards, e.g.
+// customer.*.email, items[*].card.number or metadata.**.token
+func NewPathFilter(path string, mode Mode) *Filter {
+	return &Filter{Path: path, RedactFunc: mode}
+}
+
 func filterWithFields(filter *Filter) []*Filter {
 	if filter.Field == "" {
 		return []*Filter{filter}
@@ -143,13 +216,19 @@ func filterWithFields(filter *Filter) []*Filter {
 
 	field := strcase.LowerCamelCase(filter.Field)
 	return []*Filter{
-		NewFilter(filter.Struct, field, filter.RedactFunc),
-		NewFilter(filter.Struct, strcase.SnakeCase(field), filter.RedactFunc),
+		{Struct: filter.Struct, Field: field, RedactFunc: filter.RedactFunc},
+		{Struct: filter.Struct, Field: strcase.SnakeCase(field), RedactFunc: filter.RedactFunc},
 	}
 }
 
 func (r *Redactor) AddFilters(filters ...*Filter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	for _, f := range filters {
+		if f.Path != "" {
+			r.PathFilters = append(r.PathFilters, f)
+			continue
+		}
 		if f.Struct == nil {
 			r.GeneralFieldFilters = append(r.GeneralFieldFilters, filterWithFields(f)...)
 			continue
@@ -164,72 +243,168 @@ func (r *Redactor) AddFilters(filters ...*Filter) {
 			r.StructFieldFilters = append(r.StructFieldFilters, filterWithFields(f)...)
 		}
 	}
+	r.compile()
 }
 
-func (r *Redactor) filterStruct(dataT reflect.Type) (bool, *Filter) {
+// WithScanner returns a redactor with the filters of r, including the ones added afterwards, and the scanner
+// instead of the one of r, e.g. to scan log entries without affecting other users of r
+func (r *Redactor) WithScanner(scanner *Scanner) *Redactor {
+	return &Redactor{parent: r, scanner: scanner}
+}
+
+// compile builds the lookups of filters with empty plans and swaps them in, the first added filter wins.
+// mu must be held.
+func (r *Redactor) compile() {
+	state := &redactorState{
+		scanner:            r.scanner,
+		structLookup:       map[reflect.Type]*Filter{},
+		structFieldLookup:  map[structFieldKey]*Filter{},
+		structFieldTypes:   map[reflect.Type]bool{},
+		generalFieldLookup: map[string]*Filter{},
+	}
+	if r.parent != nil {
+		base := r.parent.load()
+		state.base = base
+		state.pathPatterns = append(state.pathPatterns, base.pathPatterns...)
+		maps.Copy(state.structLookup, base.structLookup)
+		maps.Copy(state.structFieldLookup, base.structFieldLookup)
+		maps.Copy(state.structFieldTypes, base.structFieldTypes)
+		maps.Copy(state.generalFieldLookup, base.generalFieldLookup)
+	}
+
+	for _, f := range r.PathFilters {
+		// invalid paths never match, use ParsePolicy to validate them upfront
+		if segments, err := parsePath(f.Path); err == nil {
+			state.pathPatterns = append(state.pathPatterns, &pathPattern{filter: f, segments: segments})
+		}
+	}
+
 	for _, f := range r.StructFilters {
-		if dataT == f.Struct {
-			return true, f
+		if _, ok := state.structLookup[f.Struct]; !ok {
+			state.structLookup[f.Struct] = f
 		}
 	}
-	return false, nil
-}
 
-func (r *Redactor) filterField(dataT reflect.Type, field string) (bool, *Filter) {
-	if dataT != nil {
-		for _, f := range r.StructFieldFilters {
-			if dataT == f.Struct && strings.EqualFold(field, f.Field) {
-				return true, f
-			}
+	for _, f := range r.StructFieldFilters {
+		key := structFieldKey{structType: f.Struct, field: strings.ToLower(f.Field)}
+		if _, ok := state.structFieldLookup[key]; !ok {
+			state.structFieldLookup[key] = f
 		}
+		state.structFieldTypes[f.Struct] = true
 	}
 
 	for _, f := range r.GeneralFieldFilters {
-		if strings.EqualFold(field, f.Field) {
+		key := strings.ToLower(f.Field)
+		if _, ok := state.generalFieldLookup[key]; !ok {
+			state.generalFieldLookup[key] = f
+		}
+	}
+
+	r.state.Store(state)
+}
+
+// load returns the compiled state, which is compiled on first use of redactors without AddFilters,
+// or once filters of the parent changed
+func (r *Redactor) load() *redactorState {
+	if state := r.state.Load(); state != nil && (r.parent == nil || state.base == r.parent.state.Load()) {
+		return state
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if state := r.state.Load(); state == nil || (r.parent != nil && state.base != r.parent.state.Load()) {
+		r.compile()
+	}
+	return r.state.Load()
+}
+
+func (r *redactorState) filterStruct(dataT reflect.Type) (bool, *Filter) {
+	if f, ok := r.structLookup[dataT]; ok {
+		return true, f
+	}
+	return false, nil
+}
+
+func (r *redactorState) filterField(dataT reflect.Type, field string) (bool, *Filter) {
+	field = strings.ToLower(field)
+	if dataT != nil {
+		if f, ok := r.structFieldLookup[structFieldKey{structType: dataT, field: field}]; ok {
 			return true, f
 		}
 	}
 
+	if f, ok := r.generalFieldLookup[field]; ok {
+		return true, f
+	}
+
 	return false, nil
 }
 
+// fieldFilter returns the filter matching the field, nil if none
+func (r *redactorState) fieldFilter(dataT reflect.Type, field string) *Filter {
+	_, f := r.filterField(dataT, field)
+	return f
+}
+
 func (r *Redactor) Redact(T any) any {
 	if T == nil {
 		return nil
 	}
 
-	redacted, _ := r.redact(nil, "", reflect.ValueOf(T))
+	rT := reflect.ValueOf(T)
+	state := r.load()
+	redacted, _ := state.redact(state.plan(rT.Type()), state.fieldFilter(nil, ""), newPathStates(state.pathPatterns), rT)
 	return redacted
 }
 
-func (r *Redactor) redact(structType reflect.Type, field string, rT reflect.Value) (any, bool) {
+// redact walks the value with the plan of its type, filter is the one matching the
+// field holding the value which applies to any non-struct value, paths are the
+// path filters still matching the walk
+func (r *redactorState) redact(plan *typePlan, filter *Filter, paths pathStates, rT reflect.Value) (any, bool) {
 	if !rT.IsValid() {
 		return nil, false
 	}
 
-	if rT.Kind() == reflect.Pointer && rT.IsNil() {
+	if rT.Kind() == reflect.Pointer {
+		if rT.IsNil() {
+			return rT.Interface(), false
+		}
+		rT = rT.Elem()
+		plan = plan.elem
+	}
+
+	if len(paths) == 0 && plan.isClean() && (filter == nil || plan.kind == reflect.Struct) {
 		return rT.Interface(), false
 	}
 
-	rT = reflect.Indirect(rT)
-	switch rT.Kind() {
+	switch plan.kind {
 	case reflect.Struct:
-		if ok, filter := r.filterStruct(rT.Type()); ok {
-			return filter.RedactFunc(rT), true
+		if plan.structFilter != nil {
+			return plan.structFilter.RedactFunc(rT), true
 		}
 
-		nField := rT.Type().NumField()
-		redacted := make(map[string]any, nField)
+		redacted := make(map[string]any, len(plan.fields))
 		var someReacted bool
-		for i := 0; i < nField; i++ {
+		for _, fp := range plan.fields {
 			var fieldReacted bool
-			field := rT.Field(i)
-			fieldType := rT.Type().Field(i)
-			if !field.CanInterface() {
+			field := rT.Field(fp.index)
+			if fp.tagged {
+				if fp.keep {
+					redacted[fp.key] = field.Interface()
+					continue
+				}
+				redacted[fp.key] = fp.mode()(field)
+				someReacted = true
 				continue
 			}
 
-			redacted[fieldKey(fieldType)], fieldReacted = r.redact(rT.Type(), fieldKey(fieldType), field)
+			fieldPaths := paths.field(fp.key)
+			if f := fieldPaths.matched(); f != nil {
+				redacted[fp.key] = f.RedactFunc(field)
+				someReacted = true
+				continue
+			}
+
+			redacted[fp.key], fieldReacted = r.redact(fp.plan, fp.filter, fieldPaths, field)
 			someReacted = someReacted || fieldReacted
 		}
 		if !someReacted {
@@ -238,7 +413,7 @@ func (r *Redactor) redact(structType reflect.Type, field string, rT reflect.Valu
 
 		return redacted, true
 	case reflect.Map:
-		if ok, filter := r.filterField(structType, field); ok {
+		if filter != nil {
 			return filter.RedactFunc(rT), true
 		}
 
@@ -248,7 +423,15 @@ func (r *Redactor) redact(structType reflect.Type, field string, rT reflect.Valu
 		redacted := make(map[string]any, length)
 		for i := 0; i < length; i++ {
 			var fieldReacted bool
-			redacted[mapKey(keys[i])], fieldReacted = r.redact(rT.Type(), mapKey(keys[i]), rT.MapIndex(keys[i]))
+			key := mapKey(keys[i])
+			keyPaths := paths.field(key)
+			if f := keyPaths.matched(); f != nil {
+				redacted[key] = f.RedactFunc(rT.MapIndex(keys[i]))
+				someReacted = true
+				continue
+			}
+
+			redacted[key], fieldReacted = r.redact(plan.elem, r.fieldFilter(plan.typ, key), keyPaths, rT.MapIndex(keys[i]))
 			someReacted = someReacted || fieldReacted
 		}
 		if !someReacted {
@@ -257,7 +440,7 @@ func (r *Redactor) redact(structType reflect.Type, field string, rT reflect.Valu
 
 		return redacted, true
 	case reflect.Slice, reflect.Array:
-		if ok, filter := r.filterField(structType, field); ok {
+		if filter != nil {
 			return filter.RedactFunc(rT), true
 		}
 
@@ -267,7 +450,14 @@ func (r *Redactor) redact(structType reflect.Type, field string, rT reflect.Valu
 		var someReacted bool
 		for i := 0; i < length; i++ {
 			var fieldReacted bool
-			redacted[i], fieldReacted = r.redact(structType, field, rT.Index(i))
+			elemPaths := paths.index(i)
+			if f := elemPaths.matched(); f != nil {
+				redacted[i] = f.RedactFunc(rT.Index(i))
+				someReacted = true
+				continue
+			}
+
+			redacted[i], fieldReacted = r.redact(plan.elem, nil, elemPaths, rT.Index(i))
 			someReacted = someReacted || fieldReacted
 		}
 		if !someReacted {
@@ -276,12 +466,29 @@ func (r *Redactor) redact(structType reflect.Type, field string, rT reflect.Valu
 
 		return redacted, true
 	case reflect.Interface:
-		return r.redact(structType, field, rT.Elem())
+		elem := rT.Elem()
+		if !elem.IsValid() {
+			return nil, false
+		}
+		if err, ok := elem.Interface().(error); ok && filter == nil && len(paths) == 0 && r.scanner != nil {
+			if !(elem.Kind() == reflect.Pointer && elem.IsNil()) {
+				if msg, found := r.scanner.Scan(err.Error()); found {
+					return msg, true
+				}
+			}
+		}
+		return r.redact(r.plan(elem.Type()), filter, paths, elem)
 	}
 
-	if ok, filter := r.filterField(structType, field); ok {
+	if filter != nil {
 		return filter.RedactFunc(rT), true
 	}
 
+	if plan.kind == reflect.String && r.scanner != nil {
+		if scanned, found := r.scanner.Scan(rT.String()); found {
+			return scanned, true
+		}
+	}
+
 	return rT.Interface(), false
 }
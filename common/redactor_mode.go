@@ -0,0 +1,245 @@
+package common
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// HMACKeyEnv is the secret of HMACRedact, HMACKeyIDEnv identifies the key in the digests across key rotations
+	HMACKeyEnv   = "REDACT_HMAC_KEY"
+	HMACKeyIDEnv = "REDACT_HMAC_KEY_ID"
+
+	defaultHMACKeyID = "default"
+
+	// TokenPrefix is the prefix of values replaced by a token of the vault
+	TokenPrefix = "token:"
+)
+
+func init() {
+	RegisterMode("hmac", HMACRedact)
+	RegisterMode("card", CardRedact)
+	RegisterMode("phone", PhoneRedact)
+}
+
+// stringOf returns the string representation of the value, nil values are kept as-is
+func stringOf(rT reflect.Value) (string, any, bool) {
+	if !rT.IsValid() {
+		return "", nil, false
+	}
+	if rT.Kind() == reflect.Pointer && rT.IsNil() {
+		return "", rT.Interface(), false
+	}
+	return fmt.Sprint(reflect.Indirect(rT).Interface()), nil, true
+}
+
+type hmacKey struct {
+	id  string
+	key []byte
+}
+
+var defaultHMACKey atomic.Pointer[hmacKey]
+
+// SetHMACKey overrides the key of HMACRedact read from REDACT_HMAC_KEY and REDACT_HMAC_KEY_ID
+func SetHMACKey(keyID string, key []byte) {
+	if keyID == "" {
+		keyID = defaultHMACKeyID
+	}
+	defaultHMACKey.Store(&hmacKey{id: keyID, key: key})
+}
+
+func loadHMACKey() *hmacKey {
+	if key := defaultHMACKey.Load(); key != nil {
+		return key
+	}
+	// not cached until configured, as env files might be loaded after the first redaction
+	secret := os.Getenv(HMACKeyEnv)
+	if secret == "" {
+		return nil
+	}
+	SetHMACKey(os.Getenv(HMACKeyIDEnv), []byte(secret))
+	return defaultHMACKey.Load()
+}
+
+// NewHMACMode replaces values with the keyed HMAC-SHA256 digest, e.g. hmac:<key id>:<hex>,
+// equal values are correlatable as long as the key is not rotated
+func NewHMACMode(keyID string, key []byte) Mode {
+	return func(rT reflect.Value) any {
+		str, value, ok := stringOf(rT)
+		if !ok {
+			return value
+		}
+		return hmacDigest(keyID, key, str)
+	}
+}
+
+func hmacDigest(keyID string, key []byte, str string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(str))
+	return "hmac:" + keyID + ":" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// HMACRedact is the HMAC mode keyed by REDACT_HMAC_KEY, values are fully redacted without a key
+// as unkeyed digests of low entropy values are reversible
+func HMACRedact(rT reflect.Value) any {
+	str, value, ok := stringOf(rT)
+	if !ok {
+		return value
+	}
+	key := loadHMACKey()
+	if key == nil {
+		return FullRedact(rT)
+	}
+	return hmacDigest(key.id, key.key, str)
+}
+
+// maskDigits masks all digits but the last n, other characters are kept for the format
+// e.g. 4111-1111-1111-1234 => ****-****-****-1234
+func maskDigits(str string, keep int) string {
+	digits := 0
+	for _, c := range str {
+		if c >= '0' && c <= '9' {
+			digits++
+		}
+	}
+
+	var b strings.Builder
+	b.Grow(len(str))
+	for _, c := range str {
+		if c >= '0' && c <= '9' {
+			if digits > keep {
+				c = '*'
+			}
+			digits--
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// CardRedact keeps the last 4 digits of card numbers, numbers too short to be a card are fully masked
+func CardRedact(rT reflect.Value) any {
+	str, value, ok := stringOf(rT)
+	if !ok {
+		return value
+	}
+	keep := 4
+	if len(strings.Map(keepDigit, str)) < 12 {
+		keep = 0
+	}
+	return maskDigits(str, keep)
+}
+
+// PhoneRedact keeps the leading + and the last 3 digits of phone numbers, e.g. +852 9123 4567 => +*** **** *567
+func PhoneRedact(rT reflect.Value) any {
+	str, value, ok := stringOf(rT)
+	if !ok {
+		return value
+	}
+	keep := 3
+	if len(strings.Map(keepDigit, str)) < 7 {
+		keep = 0
+	}
+	return maskDigits(str, keep)
+}
+
+func keepDigit(c rune) rune {
+	if c >= '0' && c <= '9' {
+		return c
+	}
+	return -1
+}
+
+// TokenVault swaps values with tokens, detokenization should be limited to authorized lookups
+type TokenVault interface {
+	Tokenize(value string) (string, error)
+	Detokenize(token string) (string, error)
+}
+
+// NewTokenMode replaces values with tokens of the vault, e.g. token:<token>, values are fully
+// redacted if the vault fails
+func NewTokenMode(vault TokenVault) Mode {
+	return func(rT reflect.Value) any {
+		str, value, ok := stringOf(rT)
+		if !ok {
+			return value
+		}
+		token, err := vault.Tokenize(str)
+		if err != nil {
+			return FullRedact(rT)
+		}
+		return TokenPrefix + token
+	}
+}
+
+// SetTokenVault registers the `token` mode backed by the vault
+func SetTokenVault(vault TokenVault) {
+	RegisterMode("token", NewTokenMode(vault))
+}
+
+// Detokenize returns the original value of a redacted value of the token mode
+func Detokenize(vault TokenVault, redacted string) (string, error) {
+	token, found := strings.CutPrefix(redacted, TokenPrefix)
+	if !found {
+		return "", fmt.Errorf("%q is not a token", redacted)
+	}
+	return vault.Detokenize(token)
+}
+
+var ErrTokenNotFound = errors.New("token not found")
+
+// MemoryTokenVault keeps tokens in memory for the lifetime of the process, equal values share a token
+type MemoryTokenVault struct {
+	tokens map[string]string
+	values map[string]string
+	mu     sync.RWMutex
+}
+
+func NewMemoryTokenVault() *MemoryTokenVault {
+	return &MemoryTokenVault{
+		tokens: map[string]string{},
+		values: map[string]string{},
+	}
+}
+
+func (v *MemoryTokenVault) Tokenize(value string) (string, error) {
+	v.mu.RLock()
+	token, ok := v.tokens[value]
+	v.mu.RUnlock()
+	if ok {
+		return token, nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if token, ok := v.tokens[value]; ok {
+		return token, nil
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token = hex.EncodeToString(b)
+	v.tokens[value] = token
+	v.values[token] = value
+	return token, nil
+}
+
+func (v *MemoryTokenVault) Detokenize(token string) (string, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	value, ok := v.values[token]
+	if !ok {
+		return "", ErrTokenNotFound
+	}
+	return value, nil
+}
@@ -0,0 +1,182 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Path syntax of path filters
+//
+//	customer.email       field or map key, matched case-insensitively and regardless of snake/camel case
+//	customer.*.email     * matches any single field or map key
+//	items[*].number      [*] matches any element of a slice or array, [0] matches the first one
+//	metadata.**.token    ** matches zero or more fields, keys or elements
+//
+// Elements of slices are transparent to field segments, so items.number matches items[*].number as well.
+type pathSegmentKind int
+
+const (
+	pathSegmentName pathSegmentKind = iota
+	pathSegmentAny
+	pathSegmentDeep
+	pathSegmentIndex
+)
+
+type pathSegment struct {
+	kind pathSegmentKind
+	name string
+	// -1 for any index
+	index int
+}
+
+type pathPattern struct {
+	filter   *Filter
+	segments []pathSegment
+}
+
+// normalizePathName makes field names comparable regardless of case, e.g. accessToken, access_token and AccessToken
+func normalizePathName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+func parsePath(path string) ([]pathSegment, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path is empty")
+	}
+
+	var segments []pathSegment
+	for _, part := range strings.Split(path, ".") {
+		name := part
+		var indexes []string
+		if i := strings.Index(part, "["); i >= 0 {
+			name = part[:i]
+			rest := part[i:]
+			for rest != "" {
+				end := strings.Index(rest, "]")
+				if rest[0] != '[' || end < 0 {
+					return nil, fmt.Errorf("invalid index in path %q", path)
+				}
+				indexes = append(indexes, rest[1:end])
+				rest = rest[end+1:]
+			}
+		}
+
+		switch name {
+		case "":
+			if len(indexes) == 0 {
+				return nil, fmt.Errorf("empty segment in path %q", path)
+			}
+		case "*":
+			segments = append(segments, pathSegment{kind: pathSegmentAny})
+		case "**":
+			segments = append(segments, pathSegment{kind: pathSegmentDeep})
+		default:
+			segments = append(segments, pathSegment{kind: pathSegmentName, name: normalizePathName(name)})
+		}
+
+		for _, index := range indexes {
+			if index == "*" || index == "" {
+				segments = append(segments, pathSegment{kind: pathSegmentIndex, index: -1})
+				continue
+			}
+			i, err := strconv.Atoi(index)
+			if err != nil || i < 0 {
+				return nil, fmt.Errorf("invalid index %q in path %q", index, path)
+			}
+			segments = append(segments, pathSegment{kind: pathSegmentIndex, index: i})
+		}
+	}
+	return segments, nil
+}
+
+// pathCursor is the position of the walk in a pattern
+type pathCursor struct {
+	pattern *pathPattern
+	pos     int
+}
+
+// pathStates are the cursors of patterns which are still matching the walk
+type pathStates []pathCursor
+
+func newPathStates(patterns []*pathPattern) pathStates {
+	var states pathStates
+	for _, p := range patterns {
+		states = states.add(pathCursor{pattern: p, pos: 0})
+	}
+	return states
+}
+
+// add appends the cursor and the cursors skipping ** segments, as ** matches zero segment
+func (s pathStates) add(c pathCursor) pathStates {
+	for _, existing := range s {
+		if existing == c {
+			return s
+		}
+	}
+	s = append(s, c)
+	if c.pos < len(c.pattern.segments) && c.pattern.segments[c.pos].kind == pathSegmentDeep {
+		s = s.add(pathCursor{pattern: c.pattern, pos: c.pos + 1})
+	}
+	return s
+}
+
+// matched returns the filter of the first pattern fully matched
+func (s pathStates) matched() *Filter {
+	for _, c := range s {
+		if c.pos == len(c.pattern.segments) {
+			return c.pattern.filter
+		}
+	}
+	return nil
+}
+
+// field advances the states by a field name or map key
+func (s pathStates) field(name string) pathStates {
+	if len(s) == 0 {
+		return nil
+	}
+
+	name = normalizePathName(name)
+	var next pathStates
+	for _, c := range s {
+		if c.pos == len(c.pattern.segments) {
+			continue
+		}
+		switch segment := c.pattern.segments[c.pos]; segment.kind {
+		case pathSegmentDeep:
+			next = next.add(c)
+		case pathSegmentAny:
+			next = next.add(pathCursor{pattern: c.pattern, pos: c.pos + 1})
+		case pathSegmentName:
+			if segment.name == name {
+				next = next.add(pathCursor{pattern: c.pattern, pos: c.pos + 1})
+			}
+		}
+	}
+	return next
+}
+
+// index advances the states by an element of slice or array
+func (s pathStates) index(i int) pathStates {
+	if len(s) == 0 {
+		return nil
+	}
+
+	var next pathStates
+	for _, c := range s {
+		if c.pos == len(c.pattern.segments) {
+			continue
+		}
+		switch segment := c.pattern.segments[c.pos]; segment.kind {
+		case pathSegmentIndex:
+			if segment.index < 0 || segment.index == i {
+				next = next.add(pathCursor{pattern: c.pattern, pos: c.pos + 1})
+			}
+		default:
+			// elements are transparent to field segments
+			next = next.add(c)
+		}
+	}
+	return next
+}
@@ -0,0 +1,162 @@
+package common
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+// typePlan is the redaction plan of a type compiled once per state of Redactor, so the walk does not
+// look up filters for every field of every value
+type typePlan struct {
+	typ  reflect.Type
+	kind reflect.Kind
+
+	// struct
+	structFilter *Filter
+	fields       []fieldPlan
+
+	// pointer, slice, array and map value
+	elem *typePlan
+
+	// whether the type itself may cause a redaction, regardless of the types it refers to
+	sensitive bool
+
+	// 0: unknown, 1: clean, 2: not clean
+	clean int32
+}
+
+type fieldPlan struct {
+	index  int
+	key    string
+	filter *Filter
+	plan   *typePlan
+
+	// declared by struct tag or protobuf field option
+	tagged  bool
+	keep    bool
+	tagName string
+	tagMode Mode
+}
+
+// mode returns the mode declared on the field
+func (fp fieldPlan) mode() Mode {
+	if fp.tagMode != nil {
+		return fp.tagMode
+	}
+	return modeOfName(fp.tagName)
+}
+
+// isClean returns whether values of the type are proven to contain nothing to redact,
+// which are returned as-is without walking
+func (p *typePlan) isClean() bool {
+	switch atomic.LoadInt32(&p.clean) {
+	case 1:
+		return true
+	case 2:
+		return false
+	}
+
+	clean := !p.reachesSensitive(map[*typePlan]bool{})
+	if clean {
+		atomic.StoreInt32(&p.clean, 1)
+	} else {
+		atomic.StoreInt32(&p.clean, 2)
+	}
+	return clean
+}
+
+func (p *typePlan) reachesSensitive(visited map[*typePlan]bool) bool {
+	if p == nil || visited[p] {
+		return false
+	}
+	visited[p] = true
+
+	if p.sensitive {
+		return true
+	}
+	if p.elem.reachesSensitive(visited) {
+		return true
+	}
+	for _, fp := range p.fields {
+		if fp.plan.reachesSensitive(visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// plan returns the cached plan of the type, or compiles one
+func (r *redactorState) plan(t reflect.Type) *typePlan {
+	if plan, ok := r.plans.Load(t); ok {
+		return plan.(*typePlan)
+	}
+
+	building := map[reflect.Type]*typePlan{}
+	plan := r.compilePlan(t, building)
+	for t, p := range building {
+		r.plans.LoadOrStore(t, p)
+	}
+	return plan
+}
+
+func (r *redactorState) compilePlan(t reflect.Type, building map[reflect.Type]*typePlan) *typePlan {
+	if plan, ok := r.plans.Load(t); ok {
+		return plan.(*typePlan)
+	}
+	if plan, ok := building[t]; ok {
+		return plan
+	}
+
+	plan := &typePlan{typ: t, kind: t.Kind()}
+	building[t] = plan
+
+	switch plan.kind {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		plan.elem = r.compilePlan(t.Elem(), building)
+	case reflect.Map:
+		plan.elem = r.compilePlan(t.Elem(), building)
+		// keys are only known at runtime
+		plan.sensitive = len(r.generalFieldLookup) > 0 || r.structFieldTypes[t]
+	case reflect.Interface:
+		// the dynamic type is only known at runtime
+		plan.sensitive = true
+	case reflect.String:
+		// free text might contain secrets
+		plan.sensitive = r.scanner != nil
+	case reflect.Struct:
+		if _, f := r.filterStruct(t); f != nil {
+			plan.structFilter = f
+			plan.sensitive = true
+			break
+		}
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			fp := fieldPlan{index: i, key: fieldKey(field)}
+			if mode, ok := tagMode(t, field); ok {
+				fp.tagged = true
+				fp.keep = mode == nil
+				if tag, ok := field.Tag.Lookup(RedactTag); ok {
+					// resolved on use as modes might be registered later
+					fp.tagName = tag
+				} else {
+					fp.tagMode = mode
+				}
+				plan.sensitive = plan.sensitive || !fp.keep
+				plan.fields = append(plan.fields, fp)
+				continue
+			}
+
+			fp.filter = r.fieldFilter(t, fp.key)
+			fp.plan = r.compilePlan(field.Type, building)
+			plan.sensitive = plan.sensitive || fp.filter != nil
+			plan.fields = append(plan.fields, fp)
+		}
+	}
+
+	return plan
+}
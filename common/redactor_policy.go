@@ -0,0 +1,83 @@
+package common
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policy declares filters in YAML or JSON, e.g.
+//
+//	filters:
+//	  - field: password
+//	    mode: full
+//	  - path: customer.*.email
+//	    mode: partial
+//	  - path: items[*].card.number
+//	    mode: hash
+type Policy struct {
+	Filters []PolicyFilter `yaml:"filters" json:"filters"`
+}
+
+// PolicyFilter is either a general field filter or a path filter, mode defaults to full
+type PolicyFilter struct {
+	Field string `yaml:"field" json:"field"`
+	Path  string `yaml:"path" json:"path"`
+	Mode  string `yaml:"mode" json:"mode"`
+}
+
+// ParsePolicy parses a YAML or JSON policy into filters
+func ParsePolicy(data []byte) ([]*Filter, error) {
+	var policy Policy
+	// JSON is a subset of YAML
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("invalid redaction policy: %w", err)
+	}
+
+	filters := make([]*Filter, 0, len(policy.Filters))
+	for i, f := range policy.Filters {
+		name := f.Mode
+		if name == "" {
+			name = "full"
+		}
+		mode, ok := ModeByName(name)
+		if !ok {
+			return nil, fmt.Errorf("invalid redaction policy: unknown mode %q of filter #%d", f.Mode, i)
+		}
+
+		switch {
+		case f.Field != "" && f.Path != "":
+			return nil, fmt.Errorf("invalid redaction policy: filter #%d has both field and path", i)
+		case f.Path != "":
+			if _, err := parsePath(f.Path); err != nil {
+				return nil, fmt.Errorf("invalid redaction policy: %w", err)
+			}
+			filters = append(filters, NewPathFilter(f.Path, mode))
+		case f.Field != "":
+			filters = append(filters, NewFilter(nil, f.Field, mode))
+		default:
+			return nil, fmt.Errorf("invalid redaction policy: filter #%d has neither field nor path", i)
+		}
+	}
+	return filters, nil
+}
+
+// LoadPolicyFile parses the policy file into filters
+func LoadPolicyFile(path string) ([]*Filter, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePolicy(data)
+}
+
+// LoadPolicy adds the filters of the policy file to the redactor
+func (r *Redactor) LoadPolicy(path string) error {
+	filters, err := LoadPolicyFile(path)
+	if err != nil {
+		return err
+	}
+	r.AddFilters(filters...)
+	return nil
+}
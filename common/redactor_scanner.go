@@ -0,0 +1,231 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Detector finds secrets in free text, e.g. an email inside an error message
+type Detector interface {
+	Name() string
+	// FindAll returns the [start, end) byte ranges of the secrets in the string
+	FindAll(s string) [][]int
+}
+
+// RegexDetector detects the matches of the pattern, or its first group if any
+type RegexDetector struct {
+	name     string
+	re       *regexp.Regexp
+	validate func(string) bool
+}
+
+func NewRegexDetector(name string, pattern string) (*RegexDetector, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern of detector %s: %w", name, err)
+	}
+	return &RegexDetector{name: name, re: re}, nil
+}
+
+func MustRegexDetector(name string, pattern string) *RegexDetector {
+	d, err := NewRegexDetector(name, pattern)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// WithValidation drops matches failing the validation, e.g. a checksum
+func (d *RegexDetector) WithValidation(validate func(string) bool) *RegexDetector {
+	d.validate = validate
+	return d
+}
+
+func (d *RegexDetector) Name() string {
+	return d.name
+}
+
+func (d *RegexDetector) FindAll(s string) [][]int {
+	var ranges [][]int
+	for _, match := range d.re.FindAllStringSubmatchIndex(s, -1) {
+		loc := match[:2]
+		if len(match) >= 4 && match[2] >= 0 {
+			loc = match[2:4]
+		}
+		if d.validate != nil && !d.validate(s[loc[0]:loc[1]]) {
+			continue
+		}
+		ranges = append(ranges, loc)
+	}
+	return ranges
+}
+
+var (
+	CardDetector  = MustRegexDetector("card", `\b(?:\d[ -]?){12,18}\d\b`).WithValidation(luhnValid)
+	EmailDetector = MustRegexDetector("email", `[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	// numbers in international format or with an area code in parentheses, bare digits are too ambiguous
+	PhoneDetector  = MustRegexDetector("phone", `\+\d{1,3}[ -]?(?:\(\d{1,4}\)[ -]?)?\d{2,4}(?:[ -]?\d{3,4}){1,2}\b|\(\d{2,4}\) ?\d{3,4}[ -]\d{4}\b`)
+	JWTDetector    = MustRegexDetector("jwt", `\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+	AWSKeyDetector = MustRegexDetector("aws_key", `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)
+	BearerDetector = MustRegexDetector("bearer", `(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`)
+)
+
+// DefaultDetectors returns the built-in detectors, cards are detected ahead of phones as
+// overlapping ranges are claimed by the first detector
+func DefaultDetectors() []Detector {
+	return []Detector{JWTDetector, BearerDetector, AWSKeyDetector, EmailDetector, CardDetector, PhoneDetector}
+}
+
+// luhnValid returns whether the digits of the string pass the Luhn checksum of card numbers
+func luhnValid(s string) bool {
+	var sum, n int
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n > 0 && sum%10 == 0
+}
+
+// Scanner replaces the secrets found by detectors in free text, e.g. <REDACTED:email>
+type Scanner struct {
+	mu sync.Mutex
+	// detectors are replaced as a whole on changes, as they are read by concurrent scans
+	detectors atomic.Pointer[[]Detector]
+}
+
+func NewScanner(detectors ...Detector) *Scanner {
+	s := &Scanner{}
+	s.AddDetector(detectors...)
+	return s
+}
+
+// AddDetector appends detectors, which apply to scans started afterwards
+func (s *Scanner) AddDetector(detectors ...Detector) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var current []Detector
+	if loaded := s.detectors.Load(); loaded != nil {
+		current = *loaded
+	}
+	updated := append(append(make([]Detector, 0, len(current)+len(detectors)), current...), detectors...)
+	s.detectors.Store(&updated)
+}
+
+type scanMatch struct {
+	start, end int
+	name       string
+}
+
+// Scan returns the string with secrets replaced, and whether any secret is found
+func (s *Scanner) Scan(str string) (string, bool) {
+	if str == "" {
+		return str, false
+	}
+
+	var detectors []Detector
+	if loaded := s.detectors.Load(); loaded != nil {
+		detectors = *loaded
+	}
+	var matches []scanMatch
+	for _, d := range detectors {
+		for _, loc := range d.FindAll(str) {
+			matches = append(matches, scanMatch{start: loc[0], end: loc[1], name: d.Name()})
+		}
+	}
+	if len(matches) == 0 {
+		return str, false
+	}
+
+	// matches are in order of detectors, so earlier detectors claim overlapping ranges
+	var claimed []scanMatch
+	for _, m := range matches {
+		if overlaps(claimed, m) {
+			continue
+		}
+		claimed = append(claimed, m)
+	}
+	sort.Slice(claimed, func(i, j int) bool { return claimed[i].start < claimed[j].start })
+
+	var b strings.Builder
+	var last int
+	for _, m := range claimed {
+		b.WriteString(str[last:m.start])
+		b.WriteString("<REDACTED:" + m.name + ">")
+		last = m.end
+	}
+	b.WriteString(str[last:])
+	return b.String(), true
+}
+
+// overlaps returns whether the match overlaps a claimed match
+func overlaps(claimed []scanMatch, m scanMatch) bool {
+	for _, c := range claimed {
+		if m.start < c.end && c.start < m.end {
+			return true
+		}
+	}
+	return false
+}
+
+// SetScanner enables scanning string values and error messages for secrets, nil disables it
+func (r *Redactor) SetScanner(scanner *Scanner) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.scanner = scanner
+	r.compile()
+}
+
+func (r *Redactor) Scanner() *Scanner {
+	return r.load().scanner
+}
+
+// ScanString replaces secrets in the free text, e.g. a log message
+func (r *Redactor) ScanString(str string) string {
+	scanner := r.load().scanner
+	if scanner == nil {
+		return str
+	}
+	scanned, _ := scanner.Scan(str)
+	return scanned
+}
+
+// RedactError returns an error with secrets in its message replaced, which still unwraps to the original error
+func (r *Redactor) RedactError(err error) error {
+	scanner := r.load().scanner
+	if err == nil || scanner == nil {
+		return err
+	}
+	msg, found := scanner.Scan(err.Error())
+	if !found {
+		return err
+	}
+	return &redactedError{err: err, msg: msg}
+}
+
+type redactedError struct {
+	err error
+	msg string
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
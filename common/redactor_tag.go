@@ -0,0 +1,117 @@
+package common
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// RedactTag is the struct tag declaring the redaction mode of a field, e.g.
+//
+//	type User struct {
+//		Password string `redact:"full"`
+//		Email    string `redact:"partial"`
+//		Phone    string `redact:"hash"`
+//		Address  string `redact:"-"` // never redacted, even matched by a filter
+//	}
+const RedactTag = "redact"
+
+var (
+	protoRedactOptions   []protoreflect.ExtensionType
+	protoRedactOptionsMu sync.RWMutex
+)
+
+// RegisterProtoRedactOption honors a custom protobuf field option declaring the redaction mode,
+// the option value is either a mode name (string) or a bool for full redaction, e.g.
+//
+//	extend google.protobuf.FieldOptions {
+//	  string redact = 50000;
+//	}
+//
+//	message User {
+//	  string phone = 1 [(redact) = "hash"];
+//	}
+//
+// The built-in `debug_redact` option is always honored as full redaction.
+func RegisterProtoRedactOption(option protoreflect.ExtensionType) {
+	protoRedactOptionsMu.Lock()
+	defer protoRedactOptionsMu.Unlock()
+	protoRedactOptions = append(protoRedactOptions, option)
+}
+
+// modeOfName returns the mode of a tag value, unknown names are fully redacted to be safe
+func modeOfName(name string) Mode {
+	if mode, ok := ModeByName(name); ok {
+		return mode
+	}
+	return FullRedact
+}
+
+// tagMode returns the mode declared on the field by the `redact` tag or protobuf field options.
+// A nil mode with ok means the field is never redacted.
+func tagMode(structType reflect.Type, field reflect.StructField) (Mode, bool) {
+	if tag, ok := field.Tag.Lookup(RedactTag); ok {
+		if tag == "-" {
+			return nil, true
+		}
+		return modeOfName(tag), true
+	}
+
+	if _, ok := field.Tag.Lookup("protobuf"); ok {
+		if fd := protoFieldDescriptor(structType, field); fd != nil {
+			return protoFieldMode(fd)
+		}
+	}
+
+	return nil, false
+}
+
+// protoFieldDescriptor returns the descriptor of a field of generated protobuf message struct
+func protoFieldDescriptor(structType reflect.Type, field reflect.StructField) protoreflect.FieldDescriptor {
+	msg, ok := reflect.New(structType).Interface().(protoreflect.ProtoMessage)
+	if !ok {
+		return nil
+	}
+
+	// e.g. protobuf:"bytes,1,opt,name=password,proto3"
+	for _, part := range strings.Split(field.Tag.Get("protobuf"), ",") {
+		if name, found := strings.CutPrefix(part, "name="); found {
+			return msg.ProtoReflect().Descriptor().Fields().ByName(protoreflect.Name(name))
+		}
+	}
+	return nil
+}
+
+func protoFieldMode(fd protoreflect.FieldDescriptor) (Mode, bool) {
+	options, ok := fd.Options().(*descriptorpb.FieldOptions)
+	if !ok || options == nil {
+		return nil, false
+	}
+
+	protoRedactOptionsMu.RLock()
+	defer protoRedactOptionsMu.RUnlock()
+	for _, option := range protoRedactOptions {
+		if !options.ProtoReflect().Has(option.TypeDescriptor()) {
+			continue
+		}
+		switch value := options.ProtoReflect().Get(option.TypeDescriptor()).Interface().(type) {
+		case string:
+			if value == "-" {
+				return nil, true
+			}
+			return modeOfName(value), true
+		case bool:
+			if value {
+				return FullRedact, true
+			}
+		}
+	}
+
+	if options.GetDebugRedact() {
+		return FullRedact, true
+	}
+	return nil, false
+}
@@ -0,0 +1,273 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/shoplineapp/go-app/plugins"
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxevent"
+)
+
+// Description is the dependency graph of the application
+type Description struct {
+	Types []ProvidedType
+	// Hooks are in order of appending, which is the order of starting and reverse order of stopping
+	Hooks   []LifecycleHook
+	Invokes []string
+	// Dot is the graph in DOT format
+	Dot string
+	// Err is the error of building the application
+	Err error
+}
+
+// ProvidedType is a type in the graph and where it is provided from
+type ProvidedType struct {
+	Type        string
+	Constructor string
+	Module      string
+	Plugin      string
+	Private     bool
+	// Used is whether the type is a dependency of any constructor or invoke, which is approximate
+	// for annotated constructors
+	Used bool
+}
+
+// LifecycleHook is a hook appended by a constructor or invoke
+type LifecycleHook struct {
+	OnStart string
+	OnStop  string
+	Caller  string
+}
+
+// Unused returns the provided types which are not a dependency of any constructor or invoke
+func (d *Description) Unused() []ProvidedType {
+	unused := []ProvidedType{}
+	for _, t := range d.Types {
+		if !t.Used {
+			unused = append(unused, t)
+		}
+	}
+	return unused
+}
+
+// Print writes the description in sections of types, unused providers, hooks, invokes and the error
+func (d *Description) Print(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPES\nTYPE\tCONSTRUCTOR\tMODULE\tPLUGIN")
+	for _, t := range d.Types {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", t.Type, t.Constructor, orDash(t.Module), orDash(t.Plugin))
+	}
+	fmt.Fprintln(tw, "\nUNUSED\nTYPE\tCONSTRUCTOR")
+	for _, t := range d.Unused() {
+		fmt.Fprintf(tw, "%s\t%s\n", t.Type, t.Constructor)
+	}
+	fmt.Fprintln(tw, "\nHOOKS\nONSTART\tONSTOP\tCALLER")
+	for _, h := range d.Hooks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", orDash(h.OnStart), orDash(h.OnStop), h.Caller)
+	}
+	fmt.Fprintln(tw, "\nINVOKES")
+	for _, invoke := range d.Invokes {
+		fmt.Fprintln(tw, invoke)
+	}
+	tw.Flush()
+	if d.Err != nil {
+		fmt.Fprintf(w, "\nERROR\n%v\n", d.Err)
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// Describe builds the application with funcs invoked, without starting it, and describes the graph. Constructors
+// run as they do by Run, so hooks appended by them are described.
+func (app *Application) Describe(funcs ...interface{}) *Description {
+	d := &Description{}
+	role, err := app.selectedRole(false)
+	if err != nil {
+		d.Err = err
+		return d
+	}
+	p, err := app.plan(role, funcs)
+	if err != nil {
+		d.Err = err
+		return d
+	}
+
+	recorder := &describeRecorder{description: d}
+	var graph fx.DotGraph
+	fxApp := fx.New(
+		// populated before the invokes which may fail
+		fx.Populate(&graph),
+		app.build(role, funcs),
+		// the latest logger is used
+		fx.WithLogger(func() fxevent.Logger { return recorder }),
+		fx.Decorate(func(lc fx.Lifecycle) fx.Lifecycle { return &describeLifecycle{Lifecycle: lc, recorder: recorder} }),
+	)
+	d.Dot = string(graph)
+	if d.Err = fxApp.Err(); d.Err != nil {
+		if dot, err := fx.VisualizeError(d.Err); err == nil {
+			d.Dot = dot
+		}
+	}
+
+	owners := map[string]string{}
+	for _, plugin := range plugins.Registered() {
+		for _, constructor := range plugin.Constructors {
+			owners[funcName(constructor)] = plugin.Name
+		}
+		for _, constructor := range plugin.Noop {
+			owners[funcName(constructor)] = plugin.Name
+		}
+	}
+	// the fx logger depends on the logger plugin
+	consumed := map[string]bool{}
+	for _, fn := range append(append(p.constructors(), p.invokes...), append(p.modules.invokes, func(appLoggerParams) {})...) {
+		for _, t := range dependencies(fn) {
+			consumed[t] = true
+		}
+	}
+	for i, t := range d.Types {
+		d.Types[i].Plugin = owners[t.Constructor]
+		// types of fx are provided to every application
+		d.Types[i].Used = consumed[t.Type] || strings.HasPrefix(t.Constructor, "go.uber.org/fx.")
+	}
+	sort.SliceStable(d.Types, func(i, j int) bool { return d.Types[i].Type < d.Types[j].Type })
+	return d
+}
+
+// funcName is the name of the function in fx events
+func funcName(fn interface{}) string {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		return fmt.Sprint(fn)
+	}
+	return runtime.FuncForPC(v.Pointer()).Name() + "()"
+}
+
+var inType = reflect.TypeOf(fx.In{})
+
+// dependencies returns the parameter types of the function, with fields of fx.In structs
+func dependencies(fn interface{}) []string {
+	t := reflect.TypeOf(fn)
+	if t == nil || t.Kind() != reflect.Func {
+		return nil
+	}
+	types := []string{}
+	var param func(p reflect.Type)
+	param = func(p reflect.Type) {
+		if p.Kind() != reflect.Struct || !embedsIn(p) {
+			types = append(types, p.String())
+			return
+		}
+		for i := 0; i < p.NumField(); i++ {
+			field := p.Field(i)
+			if field.Type == inType {
+				continue
+			}
+			if group := field.Tag.Get("group"); group != "" && field.Type.Kind() == reflect.Slice {
+				types = append(types, field.Type.Elem().String())
+				continue
+			}
+			param(field.Type)
+		}
+	}
+	for i := 0; i < t.NumIn(); i++ {
+		param(t.In(i))
+	}
+	return types
+}
+
+func embedsIn(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.Anonymous && f.Type == inType {
+			return true
+		}
+	}
+	return false
+}
+
+// describeRecorder records the provided types, invokes and hooks of the graph
+type describeRecorder struct {
+	mu          sync.Mutex
+	description *Description
+}
+
+func (r *describeRecorder) LogEvent(event fxevent.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d := r.description
+	switch e := event.(type) {
+	case *fxevent.Provided:
+		for _, t := range e.OutputTypeNames {
+			d.Types = append(d.Types, ProvidedType{Type: t, Constructor: e.ConstructorName, Module: e.ModuleName, Private: e.Private})
+		}
+	case *fxevent.Supplied:
+		d.Types = append(d.Types, ProvidedType{Type: e.TypeName, Constructor: "fx.Supply", Module: e.ModuleName})
+	case *fxevent.Invoking:
+		d.Invokes = append(d.Invokes, e.FunctionName)
+	}
+}
+
+func (r *describeRecorder) hook(hook fx.Hook, caller string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h := LifecycleHook{Caller: caller}
+	if hook.OnStart != nil {
+		h.OnStart = funcName(hook.OnStart)
+	}
+	if hook.OnStop != nil {
+		h.OnStop = funcName(hook.OnStop)
+	}
+	r.description.Hooks = append(r.description.Hooks, h)
+}
+
+// describeLifecycle records the appended hooks without starting them
+type describeLifecycle struct {
+	fx.Lifecycle
+	recorder *describeRecorder
+}
+
+func (l *describeLifecycle) Append(hook fx.Hook) {
+	caller := "unknown"
+	if pc, _, _, ok := runtime.Caller(1); ok {
+		caller = strings.TrimSuffix(runtime.FuncForPC(pc).Name(), ".func1") + "()"
+	}
+	l.recorder.hook(hook, caller)
+	l.Lifecycle.Append(hook)
+}
+
+// describe writes the description of the graph to w, or the DOT graph only with --dot
+func (app *Application) describe(w io.Writer, args []string, funcs []interface{}) error {
+	d := app.Describe(funcs...)
+	if len(args) > 0 && args[0] == "--dot" {
+		fmt.Fprintln(w, d.Dot)
+	} else {
+		d.Print(w)
+	}
+	return d.Err
+}
+
+// describeCommand is the built-in describe command, which runs without building the graph in advance
+// so that a failing graph is still described
+func (app *Application) describeCommand() Command {
+	return Command{
+		Name:        "describe",
+		Description: "Describe the dependency graph, --dot for the DOT graph only",
+		direct: func(ctx context.Context, args Args) error {
+			return app.describe(os.Stdout, args, nil)
+		},
+	}
+}
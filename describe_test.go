@@ -0,0 +1,53 @@
+package app_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	app "github.com/shoplineapp/go-app"
+	"github.com/shoplineapp/go-app/apptest"
+)
+
+type unusedService struct{}
+
+func TestDescribe(t *testing.T) {
+	a := apptest.New(t, apptest.WithModule(&userModule{events: &[]string{}}))
+	a.AddModule(&unusedModule{})
+
+	d := a.Describe()
+	if d.Err != nil {
+		t.Fatal(d.Err)
+	}
+	types := map[string]app.ProvidedType{}
+	for _, provided := range d.Types {
+		types[provided.Type] = provided
+	}
+	if repository := types["*app_test.repository"]; repository.Module != "app_test.storageModule" || !repository.Used {
+		t.Fatalf("repository is described as %+v", repository)
+	}
+	if service := types["*app_test.unusedService"]; service.Used {
+		t.Fatalf("unused service is described as %+v", service)
+	}
+	if len(d.Hooks) < 2 || !strings.Contains(d.Dot, "digraph") {
+		t.Fatalf("hooks %v or DOT graph %q are not described", d.Hooks, d.Dot)
+	}
+
+	var out bytes.Buffer
+	d.Print(&out)
+	if !strings.Contains(out.String(), "UNUSED") || !strings.Contains(out.String(), "*app_test.unusedService") {
+		t.Fatalf("unused providers are not printed:\n%s", out.String())
+	}
+
+	failed := apptest.New(t).Describe(func(*unusedService) {})
+	if failed.Err == nil || !strings.Contains(failed.Err.Error(), "missing type: *app_test.unusedService") || failed.Dot == "" {
+		t.Fatalf("missing dependency is not described, error %v", failed.Err)
+	}
+}
+
+type unusedModule struct{}
+
+func (m *unusedModule) Controllers() []interface{} { return nil }
+func (m *unusedModule) Provide() []interface{} {
+	return []interface{}{func() *unusedService { return &unusedService{} }}
+}
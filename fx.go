@@ -2,7 +2,10 @@ package app
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"go.uber.org/fx/fxevent"
@@ -10,85 +13,317 @@ import (
 
 var fxLogger = AppLogger{}
 
+// DefaultHookBudget is the runtime of a single lifecycle hook over which a warning is logged
+const DefaultHookBudget = 5 * time.Second
+
+// slowest is the number of constructors and hooks in the startup summary
+const slowest = 5
+
+// AppLogger logs the fx events with structured fields, i.e. event, function, caller, module, runtime_ms and error
 type AppLogger struct {
 	fxevent.Logger
+
+	// Budget of a single hook, DefaultHookBudget if zero
+	Budget time.Duration
+	// Log is the logger plugin once provided, the standard logger if nil
+	Log   logrus.FieldLogger
+	hooks *hookReport
+}
+
+// NewAppLogger returns the logger which summarizes the startup and reports the runtime of stop hooks
+// once the application is stopped
+func NewAppLogger(budget time.Duration) AppLogger {
+	return AppLogger{Budget: budget, hooks: &hookReport{booted: time.Now(), executing: map[string]time.Time{}}}
+}
+
+// HookTiming is the runtime of an executed constructor or lifecycle hook
+type HookTiming struct {
+	Function string
+	Caller   string
+	Runtime  time.Duration
+	Err      error
+}
+
+type hookReport struct {
+	mu           sync.Mutex
+	booted       time.Time
+	executing    map[string]time.Time
+	constructors []HookTiming
+	starts       []HookTiming
+	stops        []HookTiming
+	stopReported bool
+}
+
+func (r *hookReport) begin(function string, caller string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.executing[function+" called by "+caller] = time.Now()
+}
+
+func (r *hookReport) end(function string, caller string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.executing, function+" called by "+caller)
+}
+
+func (r *hookReport) add(timings *[]HookTiming, timing HookTiming) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*timings = append(*timings, timing)
+}
+
+func (r *hookReport) sorted(timings *[]HookTiming) []HookTiming {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report := append([]HookTiming{}, *timings...)
+	sort.SliceStable(report, func(i, j int) bool { return report[i].Runtime > report[j].Runtime })
+	return report
+}
+
+// StopReport returns the executed stop hooks, the slowest first
+func (l AppLogger) StopReport() []HookTiming {
+	if l.hooks == nil {
+		return nil
+	}
+	return l.hooks.sorted(&l.hooks.stops)
 }
 
-func (AppLogger) LogEvent(event fxevent.Event) {
+// StartReport returns the run constructors and the executed start hooks, the slowest first
+func (l AppLogger) StartReport() (constructors []HookTiming, hooks []HookTiming) {
+	if l.hooks == nil {
+		return nil, nil
+	}
+	return l.hooks.sorted(&l.hooks.constructors), l.hooks.sorted(&l.hooks.starts)
+}
+
+// reportStop logs the runtime of the executed stop hooks once, and returns whether they are reported by the call
+func (l AppLogger) reportStop() bool {
+	if l.hooks == nil {
+		return false
+	}
+	l.hooks.mu.Lock()
+	reported := l.hooks.stopReported
+	l.hooks.stopReported = true
+	l.hooks.mu.Unlock()
+	if reported {
+		return false
+	}
+
+	for _, timing := range l.StopReport() {
+		status := "ok"
+		if timing.Err != nil {
+			status = fmt.Sprintf("failed: %v", timing.Err)
+		}
+		l.log("OnStopExecuted", logrus.Fields{"function": timing.Function, "caller": timing.Caller, "runtime": timing.Runtime, "error": timing.Err}).
+			Info(fmt.Sprintf("STOPPED in %s\t%s called by %s %s", timing.Runtime, timing.Function, timing.Caller, status))
+	}
+	return true
+}
+
+// unfinished returns the hooks which are still executing, e.g. when the timeout is exceeded
+func (l AppLogger) unfinished() []string {
+	if l.hooks == nil {
+		return nil
+	}
+	l.hooks.mu.Lock()
+	defer l.hooks.mu.Unlock()
+	hooks := []string{}
+	for hook, since := range l.hooks.executing {
+		hooks = append(hooks, fmt.Sprintf("%s running for %s", hook, time.Since(since).Round(time.Millisecond)))
+	}
+	sort.Strings(hooks)
+	return hooks
+}
+
+func (l AppLogger) log(event string, fields logrus.Fields) *logrus.Entry {
+	var log logrus.FieldLogger = logrus.StandardLogger()
+	if l.Log != nil {
+		log = l.Log
+	}
+	entry := log.WithField("event", event)
+	for key, value := range fields {
+		switch v := value.(type) {
+		case string:
+			if v == "" {
+				continue
+			}
+		case time.Duration:
+			key, value = key+"_ms", float64(v)/float64(time.Millisecond)
+		case nil:
+			continue
+		}
+		entry = entry.WithField(key, value)
+	}
+	return entry
+}
+
+func (l AppLogger) overBudget(event string, hook string, function string, caller string, runtime time.Duration) {
+	budget := l.Budget
+	if budget == 0 {
+		budget = DefaultHookBudget
+	}
+	if runtime > budget {
+		l.log(event, logrus.Fields{"function": function, "caller": caller, "runtime": runtime, "budget": budget.String()}).
+			Warn(fmt.Sprintf("HOOK %s\t\t%s called by %s took %s, over the budget of %s", hook, function, caller, runtime, budget))
+	}
+}
+
+func (l AppLogger) summarize() {
+	if l.hooks == nil {
+		return
+	}
+	constructors, hooks := l.StartReport()
+	boot := time.Since(l.hooks.booted)
+	l.log("Started", logrus.Fields{"runtime": boot, "constructors": len(constructors), "hooks": len(hooks)}).
+		Info(fmt.Sprintf("STARTUP in %s with %d constructors and %d start hooks", boot.Round(time.Millisecond), len(constructors), len(hooks)))
+	for i, timing := range constructors {
+		if i == slowest {
+			break
+		}
+		l.log("Run", logrus.Fields{"function": timing.Function, "module": timing.Caller, "runtime": timing.Runtime}).
+			Info(fmt.Sprintf("STARTUP constructor %s in %s", timing.Function, timing.Runtime))
+	}
+	for i, timing := range hooks {
+		if i == slowest {
+			break
+		}
+		l.log("OnStartExecuted", logrus.Fields{"function": timing.Function, "caller": timing.Caller, "runtime": timing.Runtime}).
+			Info(fmt.Sprintf("STARTUP hook %s called by %s in %s", timing.Function, timing.Caller, timing.Runtime))
+	}
+}
+
+func (l AppLogger) LogEvent(event fxevent.Event) {
 	switch e := event.(type) {
 	case *fxevent.OnStartExecuting:
-		logrus.Debug(fmt.Sprintf("HOOK OnStart\t\t%s executing (caller: %s)", e.FunctionName, e.CallerName))
+		l.hooks.begin(e.FunctionName, e.CallerName)
+		l.log("OnStartExecuting", logrus.Fields{"function": e.FunctionName, "caller": e.CallerName}).
+			Debug(fmt.Sprintf("HOOK OnStart\t\t%s executing (caller: %s)", e.FunctionName, e.CallerName))
 	case *fxevent.OnStartExecuted:
+		l.hooks.end(e.FunctionName, e.CallerName)
+		l.hooks.add(&l.hooks.starts, HookTiming{Function: e.FunctionName, Caller: e.CallerName, Runtime: e.Runtime, Err: e.Err})
+		entry := l.log("OnStartExecuted", logrus.Fields{"function": e.FunctionName, "caller": e.CallerName, "runtime": e.Runtime, "error": e.Err})
 		if e.Err != nil {
-			logrus.Error(fmt.Sprintf("HOOK OnStart\t\t%s called by %s failed in %s: %v", e.FunctionName, e.CallerName, e.Runtime, e.Err))
+			entry.Error(fmt.Sprintf("HOOK OnStart\t\t%s called by %s failed in %s: %v", e.FunctionName, e.CallerName, e.Runtime, e.Err))
 		} else {
-			logrus.Debug(fmt.Sprintf("HOOK OnStart\t\t%s called by %s ran successfully in %s", e.FunctionName, e.CallerName, e.Runtime))
+			entry.Debug(fmt.Sprintf("HOOK OnStart\t\t%s called by %s ran successfully in %s", e.FunctionName, e.CallerName, e.Runtime))
 		}
+		l.overBudget("OnStartExecuted", "OnStart", e.FunctionName, e.CallerName, e.Runtime)
 	case *fxevent.OnStopExecuting:
-		logrus.Debug(fmt.Sprintf("HOOK OnStop\t\t%s executing (caller: %s)", e.FunctionName, e.CallerName))
+		l.hooks.begin(e.FunctionName, e.CallerName)
+		l.log("OnStopExecuting", logrus.Fields{"function": e.FunctionName, "caller": e.CallerName}).
+			Debug(fmt.Sprintf("HOOK OnStop\t\t%s executing (caller: %s)", e.FunctionName, e.CallerName))
 	case *fxevent.OnStopExecuted:
+		l.hooks.end(e.FunctionName, e.CallerName)
+		l.hooks.add(&l.hooks.stops, HookTiming{Function: e.FunctionName, Caller: e.CallerName, Runtime: e.Runtime, Err: e.Err})
+		entry := l.log("OnStopExecuted", logrus.Fields{"function": e.FunctionName, "caller": e.CallerName, "runtime": e.Runtime, "error": e.Err})
 		if e.Err != nil {
-			logrus.Error(fmt.Sprintf("HOOK OnStop\t\t%s called by %s failed in %s: %v", e.FunctionName, e.CallerName, e.Runtime, e.Err))
+			entry.Error(fmt.Sprintf("HOOK OnStop\t\t%s called by %s failed in %s: %v", e.FunctionName, e.CallerName, e.Runtime, e.Err))
 		} else {
-			logrus.Debug(fmt.Sprintf("HOOK OnStop\t\t%s called by %s ran successfully in %s", e.FunctionName, e.CallerName, e.Runtime))
+			entry.Debug(fmt.Sprintf("HOOK OnStop\t\t%s called by %s ran successfully in %s", e.FunctionName, e.CallerName, e.Runtime))
 		}
+		l.overBudget("OnStopExecuted", "OnStop", e.FunctionName, e.CallerName, e.Runtime)
 	case *fxevent.Supplied:
+		entry := l.log("Supplied", logrus.Fields{"type": e.TypeName, "module": e.ModuleName, "error": e.Err})
 		if e.Err != nil {
-			logrus.Error(fmt.Sprintf("Failed to supply %v: %v", e.TypeName, e.Err))
+			entry.Error(fmt.Sprintf("Failed to supply %v: %v", e.TypeName, e.Err))
 		} else if e.ModuleName != "" {
-			logrus.Info(fmt.Sprintf("SUPPLY %v from module %q", e.TypeName, e.ModuleName))
+			entry.Info(fmt.Sprintf("SUPPLY %v from module %q", e.TypeName, e.ModuleName))
 		} else {
-			logrus.Info(fmt.Sprintf("SUPPLY %v", e.TypeName))
+			entry.Info(fmt.Sprintf("SUPPLY %v", e.TypeName))
 		}
 	case *fxevent.Provided:
 		for _, rtype := range e.OutputTypeNames {
+			entry := l.log("Provided", logrus.Fields{"type": rtype, "function": e.ConstructorName, "module": e.ModuleName})
 			if e.ModuleName != "" {
-				logrus.Info(fmt.Sprintf("PROVIDE plugin %v <= from module %q", rtype, e.ModuleName))
+				entry.Info(fmt.Sprintf("PROVIDE plugin %v <= from module %q", rtype, e.ModuleName))
 			} else {
-				logrus.Info(fmt.Sprintf("PROVIDE plugin %v", rtype))
+				entry.Info(fmt.Sprintf("PROVIDE plugin %v", rtype))
 			}
 		}
 		if e.Err != nil {
-			logrus.Error(fmt.Sprintf("Error after options were applied: %v", e.Err))
+			l.log("Provided", logrus.Fields{"function": e.ConstructorName, "module": e.ModuleName, "error": e.Err}).
+				Error(fmt.Sprintf("Error after options were applied: %v", e.Err))
 		}
 	case *fxevent.Decorated:
 		for _, rtype := range e.OutputTypeNames {
+			entry := l.log("Decorated", logrus.Fields{"type": rtype, "function": e.DecoratorName, "module": e.ModuleName})
 			if e.ModuleName != "" {
-				logrus.Debug(fmt.Sprintf("DECORATE %v <= %v from module %q", rtype, e.DecoratorName, e.ModuleName))
+				entry.Debug(fmt.Sprintf("DECORATE %v <= %v from module %q", rtype, e.DecoratorName, e.ModuleName))
 			} else {
-				logrus.Debug(fmt.Sprintf("DECORATE %v <= %v", rtype, e.DecoratorName))
+				entry.Debug(fmt.Sprintf("DECORATE %v <= %v", rtype, e.DecoratorName))
 			}
 		}
 		if e.Err != nil {
-			logrus.Error(fmt.Sprintf("Error after options were applied: %v", e.Err))
+			l.log("Decorated", logrus.Fields{"function": e.DecoratorName, "module": e.ModuleName, "error": e.Err}).
+				Error(fmt.Sprintf("Error after options were applied: %v", e.Err))
+		}
+	case *fxevent.Run:
+		// the caller of constructors is the module
+		l.hooks.add(&l.hooks.constructors, HookTiming{Function: e.Name, Caller: e.ModuleName, Runtime: e.Runtime, Err: e.Err})
+		entry := l.log("Run", logrus.Fields{"function": e.Name, "kind": e.Kind, "module": e.ModuleName, "runtime": e.Runtime, "error": e.Err})
+		if e.Err != nil {
+			entry.Error(fmt.Sprintf("Failed to run %s %s: %v", e.Kind, e.Name, e.Err))
+		} else {
+			entry.Debug(fmt.Sprintf("RUN %s %s in %s", e.Kind, e.Name, e.Runtime))
 		}
 	case *fxevent.Invoking:
+		entry := l.log("Invoking", logrus.Fields{"function": e.FunctionName, "module": e.ModuleName})
 		if e.ModuleName != "" {
-			logrus.Debug(fmt.Sprintf("INVOKE %s from module %q", e.FunctionName, e.ModuleName))
+			entry.Debug(fmt.Sprintf("INVOKE %s from module %q", e.FunctionName, e.ModuleName))
 		} else {
-			logrus.Debug(fmt.Sprintf("INVOKE %s", e.FunctionName))
+			entry.Debug(fmt.Sprintf("INVOKE %s", e.FunctionName))
 		}
 	case *fxevent.Invoked:
 		if e.Err != nil {
-			logrus.Error(fmt.Sprintf("Failed to invoke %v called from:\n%+vFailed: %v", e.FunctionName, e.Trace, e.Err))
+			l.log("Invoked", logrus.Fields{"function": e.FunctionName, "module": e.ModuleName, "error": e.Err}).
+				Error(fmt.Sprintf("Failed to invoke %v called from:\n%+vFailed: %v", e.FunctionName, e.Trace, e.Err))
 		}
 	case *fxevent.Stopping:
-		logrus.Warn(fmt.Sprintf("Received %s", strings.ToUpper(e.Signal.String())))
+		l.log("Stopping", logrus.Fields{"signal": strings.ToUpper(e.Signal.String())}).
+			Warn(fmt.Sprintf("Received %s", strings.ToUpper(e.Signal.String())))
 	case *fxevent.Stopped:
 		if e.Err != nil {
-			logrus.Error(fmt.Sprintf("Failed to stop cleanly: %v", e.Err))
+			l.log("Stopped", logrus.Fields{"error": e.Err}).Error(fmt.Sprintf("Failed to stop cleanly: %v", e.Err))
+			for _, hook := range l.unfinished() {
+				l.log("Stopped", logrus.Fields{"hook": hook}).Error(fmt.Sprintf("HOOK OnStop\t\t%s unfinished", hook))
+			}
+		}
+		// the report is logged by the stop hook of the application ahead of the logger plugin closing its sinks,
+		// unless the hooks are cut short by the timeout, then the logger is closed once the report is logged
+		if l.reportStop() {
+			if closer, ok := l.Log.(interface{ Close() error }); ok {
+				closer.Close()
+			}
 		}
 	case *fxevent.RollingBack:
-		logrus.Error(fmt.Sprintf("Start failed, rolling back: %v", e.StartErr))
+		l.log("RollingBack", logrus.Fields{"error": e.StartErr}).Error(fmt.Sprintf("Start failed, rolling back: %v", e.StartErr))
 	case *fxevent.RolledBack:
 		if e.Err != nil {
-			logrus.Error(fmt.Sprintf("Couldn't roll back cleanly: %v", e.Err))
+			l.log("RolledBack", logrus.Fields{"error": e.Err}).Error(fmt.Sprintf("Couldn't roll back cleanly: %v", e.Err))
 		}
 	case *fxevent.Started:
 		if e.Err != nil {
-			logrus.Error(fmt.Sprintf("Failed to start: %v", e.Err))
+			l.log("Started", logrus.Fields{"error": e.Err}).Error(fmt.Sprintf("Failed to start: %v", e.Err))
 		} else {
-			logrus.Info("Application RUNNING")
+			l.summarize()
+			l.log("Started", nil).Info("Application RUNNING")
+		}
+	case *fxevent.LoggerInitialized:
+		if e.Err != nil {
+			l.log("LoggerInitialized", logrus.Fields{"function": e.ConstructorName, "error": e.Err}).
+				Error(fmt.Sprintf("Failed to initialize the logger: %v", e.Err))
 		}
 	}
 }
@@ -0,0 +1,114 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shoplineapp/go-app/plugins/env"
+	"github.com/shoplineapp/go-app/plugins/logger"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxevent"
+	"go.uber.org/fx/fxtest"
+)
+
+func TestAppLoggerHookReport(t *testing.T) {
+	hook := test.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	l := NewAppLogger(time.Second)
+	l.LogEvent(&fxevent.OnStopExecuting{FunctionName: "grpc.Shutdown", CallerName: "grpc.New"})
+	l.LogEvent(&fxevent.OnStopExecuted{FunctionName: "grpc.Shutdown", CallerName: "grpc.New", Runtime: 2 * time.Second})
+	l.LogEvent(&fxevent.OnStopExecuting{FunctionName: "pulsar.Close", CallerName: "pulsar.New"})
+	l.LogEvent(&fxevent.OnStopExecuted{FunctionName: "pulsar.Close", CallerName: "pulsar.New", Runtime: 3 * time.Second})
+	l.LogEvent(&fxevent.OnStopExecuting{FunctionName: "sqs.Stop", CallerName: "sqs.New"})
+	l.LogEvent(&fxevent.Stopped{Err: errors.New("context deadline exceeded")})
+
+	report := l.StopReport()
+	if len(report) != 2 || report[0].Function != "pulsar.Close" || report[1].Function != "grpc.Shutdown" {
+		t.Fatalf("stop hooks are not reported slowest first but %v", report)
+	}
+
+	var warnings, unfinished, stopped int
+	for _, entry := range hook.AllEntries() {
+		switch {
+		case entry.Level == logrus.WarnLevel && strings.Contains(entry.Message, "over the budget of 1s"):
+			warnings++
+		case entry.Level == logrus.ErrorLevel && strings.Contains(entry.Message, "sqs.Stop called by sqs.New running for"):
+			unfinished++
+		case strings.HasPrefix(entry.Message, "STOPPED in "):
+			stopped++
+		}
+	}
+	if warnings != 2 || unfinished != 1 || stopped != 2 {
+		t.Fatalf("hooks are not reported, %d over budget, %d unfinished and %d stopped", warnings, unfinished, stopped)
+	}
+}
+
+func TestAppLoggerStartupSummary(t *testing.T) {
+	log, hook := test.NewNullLogger()
+	l := NewAppLogger(0)
+	l.Log = log
+
+	l.LogEvent(&fxevent.Run{Name: "env.NewEnv()", Kind: "provide", Runtime: time.Millisecond})
+	l.LogEvent(&fxevent.Run{Name: "mongodb.NewMongoStore()", Kind: "provide", ModuleName: "user", Runtime: 2 * time.Second})
+	l.LogEvent(&fxevent.OnStartExecuted{FunctionName: "grpc.Serve", CallerName: "grpc.New", Runtime: time.Second})
+	l.LogEvent(&fxevent.Invoked{FunctionName: "main.main.func1", Err: errors.New("missing type")})
+	l.LogEvent(&fxevent.Started{})
+
+	var summary, slowest *logrus.Entry
+	for _, entry := range hook.AllEntries() {
+		switch {
+		case strings.HasPrefix(entry.Message, "STARTUP in "):
+			summary = entry
+		case strings.HasPrefix(entry.Message, "STARTUP constructor ") && slowest == nil:
+			slowest = entry
+		case entry.Data["event"] == "Invoked":
+			if entry.Data["function"] != "main.main.func1" || entry.Data[logrus.ErrorKey] == nil {
+				t.Fatalf("failure is not structured but %v", entry.Data)
+			}
+		}
+	}
+	if summary == nil || summary.Data["constructors"] != 2 || summary.Data["hooks"] != 1 {
+		t.Fatalf("startup is not summarized but %v", summary)
+	}
+	if slowest == nil || slowest.Data["function"] != "mongodb.NewMongoStore()" || slowest.Data["module"] != "user" ||
+		slowest.Data["runtime_ms"] != 2000.0 {
+		t.Fatalf("slowest constructor is not reported first but %v", slowest)
+	}
+	if hook.LastEntry().Message != "Application RUNNING" {
+		t.Fatalf("application is not running but %s", hook.LastEntry().Message)
+	}
+}
+
+func TestAppLoggerStopReportOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	t.Setenv("ENVIRONMENT", "test")
+	t.Setenv("PROJECT_ROOT", t.TempDir())
+	t.Setenv("SECRETS_DIR", t.TempDir())
+	t.Setenv("GO_APP_CONFIG_REPORT", "false")
+	t.Setenv("LOG_OUTPUT", "file")
+	t.Setenv("LOG_FILE_PATH", path)
+
+	a := NewApplication()
+	a.SetPlugins(env.ProvideEnv, logger.ProvideLogger)
+	fxApp := fxtest.New(t, a.Options(func(lc fx.Lifecycle) {
+		lc.Append(fx.Hook{OnStop: func(ctx context.Context) error { return nil }})
+	}))
+	fxApp.RequireStart()
+	fxApp.RequireStop()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "STOPPED in ") {
+		t.Fatalf("stop report is not written to the output of the logger but\n%s", content)
+	}
+}
@@ -1,5 +1,14 @@
 package app
 
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.uber.org/fx"
+)
+
 type AppModule struct {
 }
 
@@ -8,6 +17,40 @@ type AppModuleInterface interface {
 	Provide() []interface{}
 }
 
+// Optional interfaces of modules
+
+// ModuleNamer names the module in the fx event log, defaults to the type name, e.g. user.UserModule
+type ModuleNamer interface {
+	Name() string
+}
+
+// ModuleImporter adds the modules that the module depends on ahead of it, modules imported by
+// several modules are added once
+type ModuleImporter interface {
+	Imports() []AppModuleInterface
+}
+
+// ModulePrivateProvider provides constructors that are only visible to the module itself
+type ModulePrivateProvider interface {
+	Private() []interface{}
+}
+
+// ModuleInvoker runs functions with dependencies injected when the application is built,
+// e.g. registering controllers to the gRPC server
+type ModuleInvoker interface {
+	Invoke() []interface{}
+}
+
+// ModuleStarter runs when the application starts, after the hooks of its invoked dependencies and imported modules
+type ModuleStarter interface {
+	OnStart(ctx context.Context) error
+}
+
+// ModuleStopper runs when the application stops, before the hooks of its invoked dependencies and imported modules
+type ModuleStopper interface {
+	OnStop(ctx context.Context) error
+}
+
 func Controllers() []interface{} {
 	return []interface{}{}
 }
@@ -15,3 +58,115 @@ func Controllers() []interface{} {
 func Provide() []interface{} {
 	return []interface{}{}
 }
+
+// ModuleName returns the name of the module
+func ModuleName(module AppModuleInterface) string {
+	if namer, ok := module.(ModuleNamer); ok {
+		return namer.Name()
+	}
+	return strings.TrimPrefix(reflect.TypeOf(module).String(), "*")
+}
+
+// moduleOption maps the module to an fx.Module
+func moduleOption(module AppModuleInterface) fx.Option {
+	options := []fx.Option{
+		fx.Provide(module.Controllers()...),
+		fx.Provide(module.Provide()...),
+	}
+	if private, ok := module.(ModulePrivateProvider); ok {
+		options = append(options, fx.Provide(append(private.Private(), fx.Private)...))
+	}
+	if invoker, ok := module.(ModuleInvoker); ok {
+		options = append(options, fx.Invoke(invoker.Invoke()...))
+	}
+
+	hook := fx.Hook{}
+	if starter, ok := module.(ModuleStarter); ok {
+		hook.OnStart = starter.OnStart
+	}
+	if stopper, ok := module.(ModuleStopper); ok {
+		hook.OnStop = stopper.OnStop
+	}
+	if hook.OnStart != nil || hook.OnStop != nil {
+		// appended after the invokes so the hooks of the dependencies are started first
+		options = append(options, fx.Invoke(func(lc fx.Lifecycle) {
+			lc.Append(hook)
+		}))
+	}
+
+	return fx.Module(ModuleName(module), options...)
+}
+
+// moduleConstructors returns the public and private constructors of the module
+func moduleConstructors(module AppModuleInterface) []interface{} {
+	constructors := append(append([]interface{}{}, module.Controllers()...), module.Provide()...)
+	if private, ok := module.(ModulePrivateProvider); ok {
+		constructors = append(constructors, private.Private()...)
+	}
+	return constructors
+}
+
+// moduleSet maps modules and their imports to fx.Module options, each type of module is added once. Imports of
+// an added type are skipped, while another instance of it added to the application is an error.
+type moduleSet struct {
+	modules      []AppModuleInterface
+	options      []fx.Option
+	types        map[reflect.Type]AppModuleInterface
+	constructors []interface{}
+	invokes      []interface{}
+}
+
+func buildModules(modules []AppModuleInterface) (*moduleSet, error) {
+	set := &moduleSet{types: map[reflect.Type]AppModuleInterface{}}
+	for _, module := range modules {
+		if err := set.add(module, nil); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// sameModule returns whether the modules are the same instance, or equal values of modules which are not pointers
+func sameModule(a, b AppModuleInterface) bool {
+	if reflect.ValueOf(a).Kind() == reflect.Ptr {
+		return a == b
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// add adds the imports of the module before the module itself
+func (s *moduleSet) add(module AppModuleInterface, importing []reflect.Type) error {
+	moduleType := reflect.TypeOf(module)
+	for i, t := range importing {
+		if t == moduleType {
+			cycle := []string{}
+			for _, t := range append(importing[i:], moduleType) {
+				cycle = append(cycle, strings.TrimPrefix(t.String(), "*"))
+			}
+			return fmt.Errorf("module import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	if added, ok := s.types[moduleType]; ok {
+		if len(importing) > 0 || sameModule(added, module) {
+			return nil
+		}
+		return fmt.Errorf("module %s is added twice with distinct instances", strings.TrimPrefix(moduleType.String(), "*"))
+	}
+
+	if importer, ok := module.(ModuleImporter); ok {
+		for _, imported := range importer.Imports() {
+			if err := s.add(imported, append(importing, moduleType)); err != nil {
+				return err
+			}
+		}
+	}
+
+	s.types[moduleType] = module
+	s.modules = append(s.modules, module)
+	s.options = append(s.options, moduleOption(module))
+	s.constructors = append(s.constructors, moduleConstructors(module)...)
+	if invoker, ok := module.(ModuleInvoker); ok {
+		s.invokes = append(s.invokes, invoker.Invoke()...)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package app_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	app "github.com/shoplineapp/go-app"
+	"github.com/shoplineapp/go-app/apptest"
+	"github.com/sirupsen/logrus"
+)
+
+type store struct{ dsn string }
+
+type repository struct{ store *store }
+
+type storageModule struct {
+	events *[]string
+}
+
+func (m *storageModule) Controllers() []interface{} { return nil }
+func (m *storageModule) Provide() []interface{} {
+	return []interface{}{func(s *store) *repository { return &repository{store: s} }}
+}
+func (m *storageModule) Private() []interface{} {
+	return []interface{}{func() *store { return &store{dsn: "memory"} }}
+}
+func (m *storageModule) OnStart(ctx context.Context) error {
+	*m.events = append(*m.events, "storage started")
+	return nil
+}
+func (m *storageModule) OnStop(ctx context.Context) error {
+	*m.events = append(*m.events, "storage stopped")
+	return nil
+}
+
+type userModule struct {
+	events *[]string
+}
+
+func (m *userModule) Name() string               { return "users" }
+func (m *userModule) Controllers() []interface{} { return nil }
+func (m *userModule) Provide() []interface{}     { return nil }
+func (m *userModule) Imports() []app.AppModuleInterface {
+	return []app.AppModuleInterface{&storageModule{events: m.events}}
+}
+func (m *userModule) OnStart(ctx context.Context) error {
+	*m.events = append(*m.events, "users started")
+	return nil
+}
+func (m *userModule) Invoke() []interface{} {
+	return []interface{}{func(r *repository) {
+		*m.events = append(*m.events, "users invoked with "+r.store.dsn)
+	}}
+}
+
+type orderModule struct {
+	events *[]string
+}
+
+func (m *orderModule) Controllers() []interface{} { return nil }
+func (m *orderModule) Provide() []interface{}     { return nil }
+func (m *orderModule) Imports() []app.AppModuleInterface {
+	return []app.AppModuleInterface{&storageModule{events: m.events}}
+}
+
+type cyclicModule struct{}
+
+func (m *cyclicModule) Controllers() []interface{} { return nil }
+func (m *cyclicModule) Provide() []interface{}     { return nil }
+func (m *cyclicModule) Imports() []app.AppModuleInterface {
+	return []app.AppModuleInterface{&cyclicModule{}}
+}
+
+func TestModule(t *testing.T) {
+	var events []string
+	t.Run("imports and hooks", func(t *testing.T) {
+		a := apptest.New(t, apptest.WithModule(&userModule{events: &events}), apptest.WithModule(&orderModule{events: &events}))
+		var r *repository
+		a.Populate(&r)
+		if r.store.dsn != "memory" {
+			t.Fatal("private provider is not used by the module")
+		}
+		if !a.Logged(logrus.InfoLevel, `PROVIDE plugin *app_test.repository <= from module "app_test.storageModule"`) {
+			t.Fatal("module is not named in the event log")
+		}
+	})
+	expected := "users invoked with memory, storage started, users started, storage stopped"
+	if strings.Join(events, ", ") != expected {
+		t.Fatalf("module hooks are not ordered by imports but %v", events)
+	}
+
+	t.Run("private providers", func(t *testing.T) {
+		a := apptest.New(t, apptest.WithModule(&storageModule{events: &[]string{}}))
+		if err := a.Validate(func(*store) {}); err == nil {
+			t.Fatal("private provider is visible outside of the module")
+		}
+	})
+
+	t.Run("duplicate modules", func(t *testing.T) {
+		storage := &storageModule{events: &[]string{}}
+		a := apptest.New(t, apptest.WithModule(storage), apptest.WithModule(storage), apptest.WithModule(&orderModule{events: &[]string{}}))
+		if err := a.Validate(func(*repository) {}); err != nil {
+			t.Fatalf("same module or its import is not added once, %v", err)
+		}
+
+		a = apptest.New(t, apptest.WithModule(&storageModule{events: &[]string{}}), apptest.WithModule(&storageModule{events: &[]string{}}))
+		err := a.Validate()
+		if err == nil || !strings.Contains(err.Error(), "module app_test.storageModule is added twice with distinct instances") {
+			t.Fatalf("distinct instances of a module are not reported but %v", err)
+		}
+	})
+
+	t.Run("import cycle", func(t *testing.T) {
+		a := apptest.New(t, apptest.WithModule(&cyclicModule{}))
+		err := a.Validate()
+		if err == nil || !strings.Contains(err.Error(), "module import cycle: app_test.cyclicModule -> app_test.cyclicModule") {
+			t.Fatalf("import cycle is not reported but %v", err)
+		}
+	})
+}
@@ -0,0 +1,187 @@
+//go:build cqrs
+// +build cqrs
+
+package cqrs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/shoplineapp/go-app/plugins"
+	"go.uber.org/fx"
+)
+
+func init() {
+	plugins.Register(plugins.Plugin{
+		Name:         "cqrs",
+		Constructors: []interface{}{NewBus},
+	})
+}
+
+// HandlersGroup is the fx value group of handlers, see AsHandler
+const HandlersGroup = "cqrs_handlers"
+
+var (
+	ErrNoHandler    = errors.New("no handler")
+	ErrInvalid      = errors.New("invalid message")
+	ErrUnauthorized = errors.New("unauthorized")
+)
+
+// isPermanent returns whether the message fails again on retries, which is rejected by the middlewares
+func isPermanent(err error) bool {
+	return errors.Is(err, ErrInvalid) || errors.Is(err, ErrUnauthorized)
+}
+
+// Kind of the message
+type Kind string
+
+const (
+	KindCommand Kind = "command"
+	KindQuery   Kind = "query"
+)
+
+// Message is the command or query passed through the middlewares
+type Message struct {
+	Kind Kind
+	// Name is the type name of the payload, e.g. orders.CreateOrder
+	Name    string
+	Payload interface{}
+}
+
+// HandleFunc handles the message and returns the result
+type HandleFunc func(ctx context.Context, msg Message) (interface{}, error)
+
+// Middleware wraps the handling of messages, e.g. Logging or Validation
+type Middleware func(next HandleFunc) HandleFunc
+
+// Handler handles a type of command or query, see CommandHandler and QueryHandler
+type Handler struct {
+	kind    Kind
+	message reflect.Type
+	handle  HandleFunc
+}
+
+// CommandHandler creates the handler of the command type C with the result R
+func CommandHandler[C any, R any](handle func(ctx context.Context, command C) (R, error)) *Handler {
+	return newHandler(KindCommand, handle)
+}
+
+// QueryHandler creates the handler of the query type Q with the result R
+func QueryHandler[Q any, R any](handle func(ctx context.Context, query Q) (R, error)) *Handler {
+	return newHandler(KindQuery, handle)
+}
+
+func newHandler[M any, R any](kind Kind, handle func(ctx context.Context, msg M) (R, error)) *Handler {
+	return &Handler{
+		kind:    kind,
+		message: reflect.TypeFor[M](),
+		handle: func(ctx context.Context, msg Message) (interface{}, error) {
+			return handle(ctx, msg.Payload.(M))
+		},
+	}
+}
+
+// AsHandler annotates the constructor returning *Handler to be collected by the bus, e.g. in Provide of modules
+//
+//	func (m *OrderModule) Provide() []interface{} {
+//		return []interface{}{
+//			cqrs.AsHandler(NewCreateOrderHandler),
+//		}
+//	}
+func AsHandler(constructor interface{}) interface{} {
+	return fx.Annotate(constructor, fx.ResultTags(fmt.Sprintf(`group:"%s"`, HandlersGroup)))
+}
+
+// Bus dispatches commands and queries to their handlers through the middlewares
+type Bus struct {
+	handlers map[reflect.Type]*Handler
+
+	mu          sync.RWMutex
+	middlewares []Middleware
+	pipelines   map[reflect.Type]HandleFunc
+}
+
+type BusParams struct {
+	fx.In
+
+	Handlers []*Handler `group:"cqrs_handlers"`
+}
+
+func NewBus(params BusParams) (*Bus, error) {
+	b := &Bus{handlers: map[reflect.Type]*Handler{}, pipelines: map[reflect.Type]HandleFunc{}}
+	var errs []error
+	for _, h := range params.Handlers {
+		if existing, ok := b.handlers[h.message]; ok {
+			errs = append(errs, fmt.Errorf("%v has handlers of %s and %s", h.message, existing.kind, h.kind))
+			continue
+		}
+		b.handlers[h.message] = h
+	}
+	return b, errors.Join(errs...)
+}
+
+// Use appends middlewares, the first one is the outermost
+func (b *Bus) Use(middlewares ...Middleware) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.middlewares = append(b.middlewares, middlewares...)
+	b.pipelines = map[reflect.Type]HandleFunc{}
+}
+
+// pipeline returns the handler of the type wrapped by the middlewares
+func (b *Bus) pipeline(kind Kind, t reflect.Type) (HandleFunc, error) {
+	h, ok := b.handlers[t]
+	if !ok || h.kind != kind {
+		return nil, fmt.Errorf("%w of %s %v", ErrNoHandler, kind, t)
+	}
+
+	b.mu.RLock()
+	pipeline, ok := b.pipelines[t]
+	b.mu.RUnlock()
+	if ok {
+		return pipeline, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	pipeline = h.handle
+	for i := len(b.middlewares) - 1; i >= 0; i-- {
+		pipeline = b.middlewares[i](pipeline)
+	}
+	b.pipelines[t] = pipeline
+	return pipeline, nil
+}
+
+func dispatch[R any, M any](ctx context.Context, b *Bus, kind Kind, msg M) (R, error) {
+	var zero R
+	t := reflect.TypeFor[M]()
+	pipeline, err := b.pipeline(kind, t)
+	if err != nil {
+		return zero, err
+	}
+	result, err := pipeline(ctx, Message{Kind: kind, Name: t.String(), Payload: msg})
+	if err != nil {
+		return zero, err
+	}
+	if result == nil {
+		return zero, nil
+	}
+	typed, ok := result.(R)
+	if !ok {
+		return zero, fmt.Errorf("result of %s %v is %T instead of %v", kind, t, result, reflect.TypeFor[R]())
+	}
+	return typed, nil
+}
+
+// Execute dispatches the command to its handler, e.g. id, err := cqrs.Execute[OrderID](ctx, bus, CreateOrder{})
+func Execute[R any, C any](ctx context.Context, b *Bus, command C) (R, error) {
+	return dispatch[R](ctx, b, KindCommand, command)
+}
+
+// Query dispatches the query to its handler, e.g. order, err := cqrs.Query[*Order](ctx, bus, GetOrder{ID: id})
+func Query[R any, Q any](ctx context.Context, b *Bus, query Q) (R, error) {
+	return dispatch[R](ctx, b, KindQuery, query)
+}
@@ -0,0 +1,123 @@
+//go:build cqrs
+// +build cqrs
+
+package cqrs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/shoplineapp/go-app/apptest"
+	"github.com/shoplineapp/go-app/plugins/cqrs"
+	"github.com/shoplineapp/go-app/plugins/logger"
+	"github.com/sirupsen/logrus"
+)
+
+type createOrder struct {
+	Item  string
+	Token string `json:"accessToken"`
+}
+
+func (c createOrder) Validate() error {
+	if c.Item == "" {
+		return errors.New("item is required")
+	}
+	return nil
+}
+
+type getOrder struct{ ID string }
+
+type order struct{ ID, Item string }
+
+type orders map[string]*order
+
+type orderModule struct{}
+
+func (m *orderModule) Controllers() []interface{} { return nil }
+func (m *orderModule) Provide() []interface{} {
+	return []interface{}{
+		func() orders { return orders{} },
+		cqrs.AsHandler(func(store orders) *cqrs.Handler {
+			return cqrs.CommandHandler(func(ctx context.Context, c createOrder) (string, error) {
+				if c.Item == "panic" {
+					panic("boom")
+				}
+				id := "order-1"
+				store[id] = &order{ID: id, Item: c.Item}
+				return id, nil
+			})
+		}),
+		cqrs.AsHandler(func(store orders) *cqrs.Handler {
+			return cqrs.QueryHandler(func(ctx context.Context, q getOrder) (*order, error) {
+				return store[q.ID], nil
+			})
+		}),
+	}
+}
+
+func TestBus(t *testing.T) {
+	var bus *cqrs.Bus
+	var log *logger.Logger
+	a := apptest.New(t, apptest.WithPlugins(cqrs.NewBus), apptest.WithModule(&orderModule{}))
+	a.Populate(&bus, &log)
+
+	metrics, err := cqrs.Metrics(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus.Use(
+		cqrs.Logging(log, nil),
+		metrics,
+		cqrs.Authorization(cqrs.AuthorizerFunc(func(ctx context.Context, msg cqrs.Message) error {
+			if c, ok := msg.Payload.(createOrder); ok && c.Item == "forbidden" {
+				return errors.New("item is forbidden")
+			}
+			return nil
+		})),
+		cqrs.Validation(),
+	)
+
+	ctx := context.Background()
+	id, err := cqrs.Execute[string](ctx, bus, createOrder{Item: "book", Token: "secret-token"})
+	if err != nil || id != "order-1" {
+		t.Fatalf("command is executed with %s, %v", id, err)
+	}
+	created, err := cqrs.Query[*order](ctx, bus, getOrder{ID: id})
+	if err != nil || created.Item != "book" {
+		t.Fatalf("query returns %v, %v", created, err)
+	}
+	entry := a.Logs().LastEntry()
+	if entry.Message != "Message handled" || entry.Data["message"] != "cqrs_test.getOrder" {
+		t.Fatalf("handled message is not logged, %v", entry)
+	}
+	payload := a.Logs().AllEntries()[len(a.Logs().AllEntries())-2].Data["payload"]
+	if fmt.Sprint(payload) != "map[Item:book accessToken:sec*****]" {
+		t.Fatalf("payload is logged as %v", payload)
+	}
+
+	if _, err := cqrs.Execute[string](ctx, bus, createOrder{}); !errors.Is(err, cqrs.ErrInvalid) {
+		t.Fatalf("invalid command is executed, %v", err)
+	}
+	if _, err := cqrs.Execute[string](ctx, bus, createOrder{Item: "forbidden"}); !errors.Is(err, cqrs.ErrUnauthorized) {
+		t.Fatalf("forbidden command is executed, %v", err)
+	}
+	if _, err := cqrs.Execute[string](ctx, bus, createOrder{Item: "panic"}); err == nil || !a.Logged(logrus.ErrorLevel, "Message failed") {
+		t.Fatalf("panic of the handler is not recovered, %v", err)
+	}
+	if _, err := cqrs.Execute[string](ctx, bus, getOrder{ID: id}); !errors.Is(err, cqrs.ErrNoHandler) {
+		t.Fatalf("query is executed as a command, %v", err)
+	}
+	if _, err := cqrs.Query[int](ctx, bus, getOrder{ID: id}); err == nil {
+		t.Fatal("result of another type is returned")
+	}
+}
+
+func TestBusDuplicateHandlers(t *testing.T) {
+	handle := func(ctx context.Context, q getOrder) (*order, error) { return nil, nil }
+	_, err := cqrs.NewBus(cqrs.BusParams{Handlers: []*cqrs.Handler{cqrs.QueryHandler(handle), cqrs.QueryHandler(handle)}})
+	if err == nil {
+		t.Fatal("duplicate handlers are registered")
+	}
+}
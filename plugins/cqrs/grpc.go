@@ -0,0 +1,32 @@
+//go:build cqrs && grpc
+// +build cqrs,grpc
+
+package cqrs
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shoplineapp/go-app/common"
+	app_grpc "github.com/shoplineapp/go-app/plugins/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// GrpcError converts errors of the middlewares to application errors with the gRPC codes, for controllers to
+// return errors of Execute and Query. Other errors are returned as they are.
+func GrpcError(ctx context.Context, err error) error {
+	var code codes.Code
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrInvalid):
+		code = codes.InvalidArgument
+	case errors.Is(err, ErrUnauthorized):
+		code = codes.PermissionDenied
+	case errors.Is(err, ErrNoHandler):
+		return app_grpc.NewApplicationError(common.GetTraceID(ctx), err, codes.Unimplemented, false)
+	default:
+		return err
+	}
+	return app_grpc.NewApplicationError(common.GetTraceID(ctx), err, code, true)
+}
@@ -0,0 +1,30 @@
+//go:build cqrs && kitex
+// +build cqrs,kitex
+
+package cqrs
+
+import (
+	"errors"
+
+	"github.com/cloudwego/kitex/pkg/kerrors"
+	"google.golang.org/grpc/codes"
+)
+
+// KitexError converts errors of the middlewares to business status errors with the codes of gRPC, for Kitex
+// handlers to return errors of Execute and Query. Other errors are returned as they are.
+func KitexError(err error) error {
+	var code codes.Code
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrInvalid):
+		code = codes.InvalidArgument
+	case errors.Is(err, ErrUnauthorized):
+		code = codes.PermissionDenied
+	case errors.Is(err, ErrNoHandler):
+		code = codes.Unimplemented
+	default:
+		return err
+	}
+	return kerrors.NewBizStatusError(int32(code), err.Error())
+}
@@ -0,0 +1,136 @@
+//go:build cqrs
+// +build cqrs
+
+package cqrs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/shoplineapp/go-app/common"
+	"github.com/shoplineapp/go-app/plugins/logger"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Component of bus logs, see logger.SamplingPolicy
+const LoggerComponent = "cqrs"
+
+// Logging logs handled messages with the payload redacted by the redactor, common.DefaultRedactor if nil.
+// Panics of the handlers are recovered and returned as errors.
+func Logging(l *logger.Logger, redactor *common.Redactor) Middleware {
+	if redactor == nil {
+		redactor = common.DefaultRedactor
+	}
+	log := l.Component(LoggerComponent)
+	return func(next HandleFunc) HandleFunc {
+		return func(ctx context.Context, msg Message) (result interface{}, err error) {
+			start := time.Now()
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+				}
+				entry := log.WithFields(logrus.Fields{
+					"kind":        msg.Kind,
+					"message":     msg.Name,
+					"payload":     logger.Unredacted(redactor.Redact(msg.Payload)),
+					"duration_ms": float64(time.Since(start).Microseconds()) / 1000,
+					"trace_id":    common.GetTraceID(ctx),
+				})
+				if err != nil {
+					entry.WithField("error", err).Error("Message failed")
+					return
+				}
+				entry.Info("Message handled")
+			}()
+			return next(ctx, msg)
+		}
+	}
+}
+
+// Validator is implemented by messages validated by the Validation middleware
+type Validator interface {
+	Validate() error
+}
+
+// Validation rejects messages implementing Validator with ErrInvalid before they are handled
+func Validation() Middleware {
+	return func(next HandleFunc) HandleFunc {
+		return func(ctx context.Context, msg Message) (interface{}, error) {
+			if v, ok := msg.Payload.(Validator); ok {
+				if err := v.Validate(); err != nil {
+					return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+				}
+			}
+			return next(ctx, msg)
+		}
+	}
+}
+
+// Authorizer decides whether the caller in the context is allowed to send the message
+type Authorizer interface {
+	Authorize(ctx context.Context, msg Message) error
+}
+
+// AuthorizerFunc is the function implementing Authorizer
+type AuthorizerFunc func(ctx context.Context, msg Message) error
+
+func (f AuthorizerFunc) Authorize(ctx context.Context, msg Message) error {
+	return f(ctx, msg)
+}
+
+// Authorization rejects messages denied by the authorizer with ErrUnauthorized
+func Authorization(authorizer Authorizer) Middleware {
+	return func(next HandleFunc) HandleFunc {
+		return func(ctx context.Context, msg Message) (interface{}, error) {
+			if err := authorizer.Authorize(ctx, msg); err != nil {
+				if !errors.Is(err, ErrUnauthorized) {
+					err = fmt.Errorf("%w: %w", ErrUnauthorized, err)
+				}
+				return nil, err
+			}
+			return next(ctx, msg)
+		}
+	}
+}
+
+// Metrics records the count and duration of handled messages by kind, message and outcome to the meter,
+// the meter of the global provider if nil, e.g. set by the opentelemetry plugin
+func Metrics(meter metric.Meter) (Middleware, error) {
+	if meter == nil {
+		meter = otel.Meter("github.com/shoplineapp/go-app/plugins/cqrs")
+	}
+	duration, err := meter.Float64Histogram("cqrs.message.duration",
+		metric.WithDescription("Duration of handled commands and queries"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return func(next HandleFunc) HandleFunc {
+		return func(ctx context.Context, msg Message) (interface{}, error) {
+			start := time.Now()
+			result, err := next(ctx, msg)
+			outcome := "ok"
+			switch {
+			case errors.Is(err, ErrInvalid):
+				outcome = "invalid"
+			case errors.Is(err, ErrUnauthorized):
+				outcome = "unauthorized"
+			case err != nil:
+				outcome = "error"
+			}
+			duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(
+				attribute.String("kind", string(msg.Kind)),
+				attribute.String("message", msg.Name),
+				attribute.String("outcome", outcome),
+			))
+			return result, err
+		}
+	}, nil
+}
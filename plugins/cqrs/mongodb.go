@@ -0,0 +1,31 @@
+//go:build cqrs && mongodb
+// +build cqrs,mongodb
+
+package cqrs
+
+import (
+	"context"
+
+	"github.com/shoplineapp/go-app/plugins/mongodb"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MongoTransaction handles commands in a transaction of the store, which is committed unless the handler fails.
+// Queries and commands sent by handlers in the transaction are passed through.
+func MongoTransaction(store *mongodb.MongoStore) Middleware {
+	return func(next HandleFunc) HandleFunc {
+		return func(ctx context.Context, msg Message) (interface{}, error) {
+			if msg.Kind != KindCommand || mongo.SessionFromContext(ctx) != nil {
+				return next(ctx, msg)
+			}
+			session, err := store.Client().StartSession()
+			if err != nil {
+				return nil, err
+			}
+			defer session.EndSession(ctx)
+			return session.WithTransaction(ctx, func(ctx mongo.SessionContext) (interface{}, error) {
+				return next(ctx, msg)
+			})
+		}
+	}
+}
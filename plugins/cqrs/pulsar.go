@@ -0,0 +1,38 @@
+//go:build cqrs && pulsar
+// +build cqrs,pulsar
+
+package cqrs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	ap "github.com/apache/pulsar-client-go/pulsar"
+)
+
+// ExecutePulsar decodes the JSON payload of the message as the command C and executes it, for Receive of Pulsar
+// consumers. Payloads which are not decoded are returned as errors to be nacked, e.g. to the DLQ of the consumer,
+// and errors of the bus are converted by PulsarError.
+//
+//	func (c *OrderConsumer) Receive(ctx context.Context, msg pulsar.ConsumerMessage) error {
+//		return cqrs.ExecutePulsar[CreateOrder](ctx, c.bus, msg)
+//	}
+func ExecutePulsar[C any](ctx context.Context, b *Bus, msg ap.Message) error {
+	var command C
+	if err := json.Unmarshal(msg.Payload(), &command); err != nil {
+		return fmt.Errorf("unable to decode %T: %w", command, err)
+	}
+	_, err := Execute[any](ctx, b, command)
+	return PulsarError(err)
+}
+
+// PulsarError drops errors of invalid and unauthorized messages, which are logged by the Logging middleware, so
+// the messages are acked instead of redelivered to fail again. Other errors, including ErrNoHandler, are returned
+// as they are to nack the messages.
+func PulsarError(err error) error {
+	if isPermanent(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,32 @@
+//go:build cqrs && sqs && sqs_worker
+// +build cqrs,sqs,sqs_worker
+
+package cqrs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// ExecuteSqs decodes the JSON message as the command C and executes it, for OnEvent of SQS event handlers.
+// See SqsDiscard for OnError.
+//
+//	func (h *OrderHandler) OnEvent(topic *sqs.Topic, message string) error {
+//		return cqrs.ExecuteSqs[CreateOrder](context.Background(), h.bus, message)
+//	}
+func ExecuteSqs[C any](ctx context.Context, b *Bus, message string) error {
+	var command C
+	if err := json.Unmarshal([]byte(message), &command); err != nil {
+		return fmt.Errorf("unable to decode %T: %w", command, err)
+	}
+	_, err := Execute[any](ctx, b, command)
+	return err
+}
+
+// SqsDiscard returns whether the message is deleted on the error, for OnError of SQS event handlers. Invalid and
+// unauthorized messages, which are logged by the Logging middleware, are deleted instead of received to fail
+// again. Messages of other errors, including ErrNoHandler, are kept on the queue, e.g. for its dead-letter queue.
+func SqsDiscard(err error) bool {
+	return isPermanent(err)
+}
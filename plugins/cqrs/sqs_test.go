@@ -0,0 +1,32 @@
+//go:build cqrs && sqs && sqs_worker
+// +build cqrs,sqs,sqs_worker
+
+package cqrs_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shoplineapp/go-app/apptest"
+	"github.com/shoplineapp/go-app/plugins/cqrs"
+)
+
+func TestExecuteSqs(t *testing.T) {
+	var bus *cqrs.Bus
+	apptest.New(t, apptest.WithPlugins(cqrs.NewBus), apptest.WithModule(&orderModule{})).Populate(&bus)
+	bus.Use(cqrs.Validation())
+
+	ctx := context.Background()
+	if err := cqrs.ExecuteSqs[createOrder](ctx, bus, `{"Item":"book"}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := cqrs.ExecuteSqs[createOrder](ctx, bus, `{}`); !cqrs.SqsDiscard(err) {
+		t.Fatalf("invalid command is not discarded, %v", err)
+	}
+	if err := cqrs.ExecuteSqs[createOrder](ctx, bus, `not json`); err == nil || cqrs.SqsDiscard(err) {
+		t.Fatalf("undecoded message is not kept, %v", err)
+	}
+	if err := cqrs.ExecuteSqs[getOrder](ctx, bus, `{"ID":"order-1"}`); err == nil || cqrs.SqsDiscard(err) {
+		t.Fatalf("message without a command handler is not kept, %v", err)
+	}
+}
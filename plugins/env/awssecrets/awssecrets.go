@@ -0,0 +1,130 @@
+//go:build awssecrets
+// +build awssecrets
+
+package awssecrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	aws_session "github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/shoplineapp/go-app/plugins/env"
+)
+
+const (
+	// SecretsManagerProvider resolves secret://aws-sm/<secret id>#<json key>
+	SecretsManagerProvider = "aws-sm"
+	// ParameterStoreProvider resolves secret://aws-ssm/<parameter name>
+	ParameterStoreProvider = "aws-ssm"
+)
+
+func init() {
+	env.RegisterSecretProvider(SecretsManagerProvider, NewSecretsManager(nil))
+	env.RegisterSecretProvider(ParameterStoreProvider, NewParameterStore(nil))
+}
+
+// sessionOf creates the session on first use, so AWS_REGION can be loaded from env files
+func sessionOf(config *aws.Config) (*aws_session.Session, error) {
+	if config == nil {
+		config = &aws.Config{Region: aws.String(os.Getenv("AWS_REGION"))}
+	}
+	return aws_session.NewSession(config)
+}
+
+// SecretsManager fetches secrets from AWS Secrets Manager
+type SecretsManager struct {
+	config *aws.Config
+	client secretsmanageriface.SecretsManagerAPI
+	mu     sync.Mutex
+}
+
+// NewSecretsManager creates the provider with the config, e.g. a custom endpoint, nil for the default config
+func NewSecretsManager(config *aws.Config) *SecretsManager {
+	return &SecretsManager{config: config}
+}
+
+func (p *SecretsManager) clientOf() (secretsmanageriface.SecretsManagerAPI, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.client == nil {
+		session, err := sessionOf(p.config)
+		if err != nil {
+			return nil, err
+		}
+		p.client = secretsmanager.New(session)
+	}
+	return p.client, nil
+}
+
+func (p *SecretsManager) Fetch(ctx context.Context, path string) (string, error) {
+	client, err := p.clientOf()
+	if err != nil {
+		return "", err
+	}
+	output, err := client.GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(path)})
+	if err != nil {
+		return "", err
+	}
+	if output.SecretString != nil {
+		return *output.SecretString, nil
+	}
+	if output.SecretBinary != nil {
+		return string(output.SecretBinary), nil
+	}
+	return "", fmt.Errorf("secret %s has no value", path)
+}
+
+// ParameterStore fetches decrypted parameters from AWS Systems Manager Parameter Store
+type ParameterStore struct {
+	config *aws.Config
+	client ssmiface.SSMAPI
+	mu     sync.Mutex
+}
+
+func NewParameterStore(config *aws.Config) *ParameterStore {
+	return &ParameterStore{config: config}
+}
+
+func (p *ParameterStore) clientOf() (ssmiface.SSMAPI, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.client == nil {
+		session, err := sessionOf(p.config)
+		if err != nil {
+			return nil, err
+		}
+		p.client = ssm.New(session)
+	}
+	return p.client, nil
+}
+
+func (p *ParameterStore) Fetch(ctx context.Context, path string) (string, error) {
+	client, err := p.clientOf()
+	if err != nil {
+		return "", err
+	}
+	// hierarchical names are absolute, e.g. secret://aws-ssm/prod/mongo/password is /prod/mongo/password
+	name := path
+	if strings.Contains(name, "/") && !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+	output, err := client.GetParameterWithContext(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", err
+	}
+	if output.Parameter == nil || output.Parameter.Value == nil {
+		return "", fmt.Errorf("parameter %s has no value", name)
+	}
+	return *output.Parameter.Value, nil
+}
@@ -0,0 +1,109 @@
+//go:build awssecrets
+// +build awssecrets
+
+package awssecrets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/shoplineapp/go-app/plugins/env"
+)
+
+// stubServer serves GetSecretValue of Secrets Manager and GetParameter of Parameter Store
+func stubServer(t *testing.T, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		var input map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&input)
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+
+		switch r.Header.Get("X-Amz-Target") {
+		case "secretsmanager.GetSecretValue":
+			if input["SecretId"] != "prod/mongo" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"__type":"ResourceNotFoundException","message":"not found"}`))
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"Name":         "prod/mongo",
+				"SecretString": `{"username":"app","password":"mongo-password"}`,
+			})
+		case "AmazonSSM.GetParameter":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"Parameter": map[string]interface{}{"Name": input["Name"], "Value": "pulsar-token"},
+			})
+		default:
+			t.Errorf("unexpected call %s", r.Header.Get("X-Amz-Target"))
+		}
+	}))
+}
+
+func stubConfig(url string) *aws.Config {
+	return &aws.Config{
+		Endpoint:    aws.String(url),
+		Region:      aws.String("ap-southeast-1"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	}
+}
+
+func TestFetch(t *testing.T) {
+	var calls int32
+	server := stubServer(t, &calls)
+	defer server.Close()
+
+	secret, err := NewSecretsManager(stubConfig(server.URL)).Fetch(context.Background(), "prod/mongo")
+	if err != nil || secret != `{"username":"app","password":"mongo-password"}` {
+		t.Fatalf("secret is not fetched but %s %v", secret, err)
+	}
+	if _, err := NewSecretsManager(stubConfig(server.URL)).Fetch(context.Background(), "unknown"); err == nil {
+		t.Fatal("unknown secret is fetched")
+	}
+
+	parameter, err := NewParameterStore(stubConfig(server.URL)).Fetch(context.Background(), "prod/pulsar/token")
+	if err != nil || parameter != "pulsar-token" {
+		t.Fatalf("parameter is not fetched but %s %v", parameter, err)
+	}
+}
+
+func TestResolveEnv(t *testing.T) {
+	var calls int32
+	server := stubServer(t, &calls)
+	defer server.Close()
+	env.RegisterSecretProvider(SecretsManagerProvider, NewSecretsManager(stubConfig(server.URL)))
+	env.RegisterSecretProvider(ParameterStoreProvider, NewParameterStore(stubConfig(server.URL)))
+
+	t.Setenv("PROJECT_ROOT", t.TempDir())
+	t.Setenv("SECRETS_DIR", t.TempDir())
+	t.Setenv("MONGO_USERNAME", "secret://aws-sm/prod/mongo#username")
+	t.Setenv("MONGO_PASSWORD", "secret://aws-sm/prod/mongo#password")
+	t.Setenv("PULSAR_TOKEN", "secret://aws-ssm/prod/pulsar/token")
+	e := env.NewEnv()
+	defer e.Close()
+
+	if e.GetEnv("MONGO_USERNAME") != "app" || e.GetEnv("MONGO_PASSWORD") != "mongo-password" || e.GetEnv("PULSAR_TOKEN") != "pulsar-token" {
+		t.Fatalf("references are not resolved")
+	}
+	if os.Getenv("MONGO_PASSWORD") != "secret://aws-sm/prod/mongo#password" {
+		t.Fatalf("secret is exported to the process env")
+	}
+	if !e.IsSecret("MONGO_PASSWORD") {
+		t.Fatalf("resolved value is not marked secret")
+	}
+
+	// cached per secret within the ttl
+	fetched := atomic.LoadInt32(&calls)
+	if err := e.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&calls) != fetched {
+		t.Fatalf("secrets are fetched again within the ttl")
+	}
+}
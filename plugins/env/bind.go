@@ -0,0 +1,248 @@
+package env
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// FieldError is the error of binding a struct field
+type FieldError struct {
+	Field string
+	Key   string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s (%s): %v", e.Key, e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+var ErrRequired = errors.New("required but not set")
+
+// Bind fills the struct pointed by cfg from environment variables declared by tags
+//
+//	type Config struct {
+//		Port     int               `env:"PORT" default:"3000"`
+//		DSN      string            `env:"DSN" required:"true"`
+//		Timeout  time.Duration     `env:"TIMEOUT" default:"30s"`
+//		Deadline time.Duration     `env:"DEADLINE" unit:"s"` // bare numbers are in seconds
+//		Hosts    []string          `env:"HOSTS" separator:";"` // comma by default
+//		Labels   map[string]string `env:"LABELS"`             // e.g. team=core,tier=1
+//		Mongo    MongoConfig       `prefix:"MONGO_"`          // nested fields are prefixed, e.g. MONGO_HOSTS
+//	}
+//
+// Fields without a value nor default are kept as-is. All invalid fields are reported in the returned error.
+func (e Env) Bind(cfg interface{}) error {
+	return e.BindPrefix("", cfg)
+}
+
+// BindPrefix binds the struct with the prefix prepended to all keys
+func (e Env) BindPrefix(prefix string, cfg interface{}) error {
+	rv := reflect.ValueOf(cfg)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("env: Bind requires a pointer to struct, got %T", cfg)
+	}
+
+	var errs []error
+	e.bindStruct(prefix, rv.Elem(), "", &errs)
+	return errors.Join(errs...)
+}
+
+func (e Env) bindStruct(prefix string, rv reflect.Value, path string, errs *[]error) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		value := rv.Field(i)
+		name := field.Name
+		if path != "" {
+			name = path + "." + field.Name
+		}
+
+		key, hasKey := field.Tag.Lookup("env")
+		if !hasKey || key == "" {
+			nested := value
+			if nested.Kind() == reflect.Pointer && nested.Type().Elem().Kind() == reflect.Struct {
+				if nested.IsNil() {
+					nested.Set(reflect.New(nested.Type().Elem()))
+				}
+				nested = nested.Elem()
+			}
+			if nested.Kind() == reflect.Struct && !isScalar(nested.Type()) {
+				e.bindStruct(prefix+field.Tag.Get("prefix"), nested, name, errs)
+			}
+			continue
+		}
+		if key == "-" {
+			continue
+		}
+		key = prefix + key
+
+		raw := e.GetEnv(key)
+		if raw == "" {
+			raw = field.Tag.Get("default")
+		}
+		if raw == "" {
+			if field.Tag.Get("required") == "true" {
+				*errs = append(*errs, &FieldError{Field: name, Key: key, Err: ErrRequired})
+			}
+			continue
+		}
+
+		if err := setValue(value, field, raw); err != nil {
+			*errs = append(*errs, &FieldError{Field: name, Key: key, Err: err})
+		}
+	}
+}
+
+// isScalar returns whether the struct type is parsed from a single value, e.g. time.Time
+func isScalar(t reflect.Type) bool {
+	return reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func setValue(value reflect.Value, field reflect.StructField, raw string) error {
+	if value.Kind() == reflect.Pointer {
+		ptr := reflect.New(value.Type().Elem())
+		if err := setValue(ptr.Elem(), field, raw); err != nil {
+			return err
+		}
+		value.Set(ptr)
+		return nil
+	}
+
+	if value.CanAddr() && value.Addr().Type().Implements(textUnmarshalerType) {
+		return value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	}
+
+	separator := field.Tag.Get("separator")
+	if separator == "" {
+		separator = ","
+	}
+
+	switch value.Kind() {
+	case reflect.Slice:
+		parts := splitList(raw, separator)
+		slice := reflect.MakeSlice(value.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setScalar(slice.Index(i), field, part); err != nil {
+				return err
+			}
+		}
+		value.Set(slice)
+		return nil
+	case reflect.Map:
+		m := reflect.MakeMap(value.Type())
+		for _, part := range splitList(raw, separator) {
+			k, v, found := strings.Cut(part, "=")
+			if !found {
+				k, v, found = strings.Cut(part, ":")
+			}
+			if !found {
+				return fmt.Errorf("invalid map entry %q, expected key=value", part)
+			}
+			key := reflect.New(value.Type().Key()).Elem()
+			if err := setScalar(key, field, strings.TrimSpace(k)); err != nil {
+				return err
+			}
+			elem := reflect.New(value.Type().Elem()).Elem()
+			if err := setScalar(elem, field, strings.TrimSpace(v)); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, elem)
+		}
+		value.Set(m)
+		return nil
+	}
+
+	return setScalar(value, field, raw)
+}
+
+func splitList(raw string, separator string) []string {
+	var parts []string
+	for _, part := range strings.Split(raw, separator) {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+func setScalar(value reflect.Value, field reflect.StructField, raw string) error {
+	if value.CanAddr() && value.Addr().Type().Implements(textUnmarshalerType) {
+		return value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	}
+
+	if value.Type() == durationType {
+		d, err := parseDuration(raw, field.Tag.Get("unit"))
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(d))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid bool %q", raw)
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(raw, 10, value.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		value.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(raw, 10, value.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", raw)
+		}
+		value.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, value.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid float %q", raw)
+		}
+		value.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
+
+// parseDuration parses Go durations, e.g. 1m30s, or bare numbers in the unit, e.g. 30 with unit s
+func parseDuration(raw string, unit string) (time.Duration, error) {
+	if unit != "" {
+		if n, err := strconv.ParseFloat(raw, 64); err == nil {
+			u, err := time.ParseDuration("1" + unit)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration unit %q", unit)
+			}
+			return time.Duration(n * float64(u)), nil
+		}
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", raw)
+	}
+	return d, nil
+}
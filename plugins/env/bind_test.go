@@ -0,0 +1,86 @@
+package env
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testMongoConfig struct {
+	Hosts    []string `env:"HOSTS" required:"true"`
+	Database string   `env:"DATABASE" default:"app"`
+}
+
+type testConfig struct {
+	Port     int               `env:"PORT" default:"3000"`
+	Debug    bool              `env:"DEBUG"`
+	Rate     float64           `env:"RATE" default:"1.0"`
+	Timeout  time.Duration     `env:"TIMEOUT" default:"30s"`
+	Deadline time.Duration     `env:"DEADLINE" unit:"s"`
+	Labels   map[string]string `env:"LABELS"`
+	Secret   *string           `env:"SECRET"`
+	Mongo    testMongoConfig   `prefix:"MONGO_"`
+	Kept     string
+}
+
+func TestBind(t *testing.T) {
+	e := &Env{defaultValues: map[string]string{"RATE": "0.5"}}
+	t.Setenv("DEBUG", "true")
+	t.Setenv("DEADLINE", "10")
+	t.Setenv("LABELS", "team=core, tier=1")
+	t.Setenv("SECRET", "secret")
+	t.Setenv("MONGO_HOSTS", "a:27017,b:27017")
+
+	cfg := testConfig{Kept: "kept"}
+	if err := e.Bind(&cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	secret := "secret"
+	expected := testConfig{
+		Port:     3000,
+		Debug:    true,
+		Rate:     0.5,
+		Timeout:  30 * time.Second,
+		Deadline: 10 * time.Second,
+		Labels:   map[string]string{"team": "core", "tier": "1"},
+		Secret:   &secret,
+		Mongo:    testMongoConfig{Hosts: []string{"a:27017", "b:27017"}, Database: "app"},
+		Kept:     "kept",
+	}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Fatalf("config is not bound as %+v but %+v", expected, cfg)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	e := &Env{defaultValues: map[string]string{}}
+	t.Setenv("PORT", "port")
+	t.Setenv("TIMEOUT", "30")
+
+	var cfg testConfig
+	err := e.Bind(&cfg)
+	if err == nil {
+		t.Fatal("invalid config is bound")
+	}
+
+	var fieldErrs []string
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var fieldErr *FieldError
+		if !errors.As(err, &fieldErr) {
+			t.Fatalf("%v is not a field error", err)
+		}
+		fieldErrs = append(fieldErrs, fieldErr.Key)
+	}
+	if !reflect.DeepEqual(fieldErrs, []string{"PORT", "TIMEOUT", "MONGO_HOSTS"}) {
+		t.Fatalf("errors are not aggregated but %v", err)
+	}
+	if !errors.Is(err, ErrRequired) {
+		t.Fatalf("missing required field is not reported")
+	}
+
+	if err := e.Bind(cfg); err == nil {
+		t.Fatal("non-pointer config is bound")
+	}
+}
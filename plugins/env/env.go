@@ -1,23 +1,27 @@
 package env
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
-	"github.com/joho/godotenv"
 	"github.com/shoplineapp/go-app/plugins"
+	"go.uber.org/fx"
 )
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewEnv)
+	plugins.Register(plugins.Plugin{Name: "env", Constructors: []interface{}{ProvideEnv}})
 }
 
 var env *Env
 
 type Env struct {
 	defaultValues map[string]string
+	state         *envState
 }
 
 func (e *Env) SetDefaultEnv(values map[string]string) {
@@ -28,6 +32,9 @@ func (e *Env) SetDefaultEnv(values map[string]string) {
 
 func (e Env) GetEnv(key string) string {
 	if value, exists := os.LookupEnv(key); exists {
+		if secret, ok := e.secretValue(key, value); ok {
+			return secret
+		}
 		return value
 	}
 	return e.defaultValues[key]
@@ -39,26 +46,104 @@ func (e Env) GetEnvInt(key string) int {
 	return intVal
 }
 
+// dotenvSources returns the env files of the environment in precedence order
+func dotenvSources() []Source {
+	projectRoot := os.Getenv("PROJECT_ROOT")
+	if len(projectRoot) == 0 {
+		projectRoot, _ = os.Getwd()
+	}
+	path := fmt.Sprintf("%s/.env", projectRoot)
+
+	environment := os.Getenv("ENVIRONMENT")
+	if environment == "" {
+		if values, err := DotenvFile(path).Load(); err == nil {
+			environment = values["ENVIRONMENT"]
+		}
+	}
+
+	if environment == "test" {
+		// tests are isolated from .env and .env.local
+		return []Source{DotenvFile(fmt.Sprintf("%s.test", path))}
+	}
+	sources := []Source{DotenvFile(path)}
+	if environment != "" {
+		sources = append(sources, DotenvFile(fmt.Sprintf("%s.%s", path, environment)))
+	}
+	return append(sources, DotenvFile(fmt.Sprintf("%s.local", path)))
+}
+
+// Lookup returns the value of the variable from the process env or the env files, without loading them,
+// for settings needed ahead of the Env plugin, e.g. selection of plugins
+func Lookup(key string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+	sources := dotenvSources()
+	for i := len(sources) - 1; i >= 0; i-- {
+		values, _ := sources[i].Load()
+		if value, exists := values[key]; exists {
+			return value
+		}
+	}
+	return ""
+}
+
+// ProvideEnv creates the env of the application, which is closed on stop of the lifecycle
+func ProvideEnv(lc fx.Lifecycle) *Env {
+	e := NewEnv()
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			e.Close()
+			return nil
+		},
+	})
+	return e
+}
+
+// NewEnv loads the layers in precedence order, defaults < .env < .env.<ENVIRONMENT> < .env.local <
+// CONFIG_FILE < SECRETS_DIR (/run/secrets) < process env. Loaded values are exported to the process env
+// for variables not set by the process, until Close.
 func NewEnv() *Env {
 	env = &Env{
 		defaultValues: map[string]string{
 			"ENVIRONMENT": "development",
 		},
+		state: &envState{processKeys: map[string]bool{}},
+	}
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		if setByProcess(key, value) {
+			env.state.processKeys[key] = true
+		}
 	}
 
-	projectRoot := os.Getenv("PROJECT_ROOT")
-	if len(projectRoot) == 0 {
-		projectRoot, _ = os.Getwd()
+	env.state.sources = dotenvSources()
+	if err := env.Reload(); err != nil {
+		log.Print("Unable to load env files ", err)
 	}
 
-	path := fmt.Sprintf("%s/.env", projectRoot)
+	// Locations of other layers might be declared by the env files
+	if configFile := env.GetEnv("CONFIG_FILE"); configFile != "" {
+		env.AddSource(ConfigFile(configFile))
+	}
+	secrets := env.GetEnv("SECRETS_DIR")
+	if secrets == "" {
+		secrets = defaultSecretsDir
+	}
+	if err := env.AddSource(SecretsDir(secrets)); err != nil {
+		log.Print("Unable to load env ", err)
+	}
 
-	if os.Getenv("ENVIRONMENT") == "test" {
-		path = fmt.Sprintf("%s.test", path)
+	// Secrets of references are cached for SECRETS_CACHE_TTL, e.g. 10m
+	if ttl, err := time.ParseDuration(env.GetEnv("SECRETS_CACHE_TTL")); err == nil && ttl > 0 {
+		env.SetSecretTTL(ttl)
 	}
 
-	if err := godotenv.Load(path); err != nil {
-		log.Print("No .env file found", err)
+	// Reload on change, e.g. ENV_WATCH_INTERVAL=10s, which refreshes expired secrets as well
+	if interval, err := time.ParseDuration(env.GetEnv("ENV_WATCH_INTERVAL")); err == nil && interval > 0 {
+		env.Watch(interval)
+	} else if env.hasSecretRefs() {
+		env.Watch(env.secretTTL())
 	}
 
 	return env
@@ -0,0 +1,171 @@
+package env
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewEnvLayers(t *testing.T) {
+	root := t.TempDir()
+	secrets := t.TempDir()
+	t.Setenv("PROJECT_ROOT", root)
+	t.Setenv("ENVIRONMENT", "staging")
+	t.Setenv("SECRETS_DIR", secrets)
+	t.Setenv("LAYER_PROCESS", "process")
+	keys := []string{"LAYER_DOTENV", "LAYER_ENVIRONMENT", "LAYER_LOCAL", "LAYER_CONFIG_LEVEL", "LAYER_CONFIG_HOSTS", "LAYER_SECRET", "CONFIG_FILE"}
+	t.Cleanup(func() {
+		for _, key := range keys {
+			os.Unsetenv(key)
+		}
+	})
+
+	config := filepath.Join(root, "config.yaml")
+	writeFile(t, filepath.Join(root, ".env"), "LAYER_DOTENV=dotenv\nLAYER_ENVIRONMENT=dotenv\nLAYER_PROCESS=dotenv\nCONFIG_FILE="+config+"\n")
+	writeFile(t, filepath.Join(root, ".env.staging"), "LAYER_ENVIRONMENT=staging\nLAYER_LOCAL=staging\n")
+	writeFile(t, filepath.Join(root, ".env.local"), "LAYER_LOCAL=local\n")
+	writeFile(t, config, "layer:\n  config_level: config\n  config-hosts: [a, b]\n")
+	writeFile(t, filepath.Join(secrets, "LAYER_SECRET"), "secret\n")
+
+	e := NewEnv()
+	defer e.Close()
+
+	expected := map[string]string{
+		"LAYER_DOTENV":       "dotenv",
+		"LAYER_ENVIRONMENT":  "staging",
+		"LAYER_LOCAL":        "local",
+		"LAYER_CONFIG_LEVEL": "config",
+		"LAYER_CONFIG_HOSTS": "a,b",
+		"LAYER_SECRET":       "secret",
+		"LAYER_PROCESS":      "process",
+	}
+	for key, value := range expected {
+		if actual := e.GetEnv(key); actual != value {
+			t.Fatalf("%s is not %s but %s", key, value, actual)
+		}
+	}
+
+	var notified []string
+	e.Subscribe("LAYER_LOCAL", func(value string) {
+		notified = append(notified, value)
+	})
+	unsubscribe := e.Subscribe("LAYER_DOTENV", func(value string) {
+		t.Fatalf("unsubscribed callback is called")
+	})
+	unsubscribe()
+
+	writeFile(t, filepath.Join(root, ".env.local"), "LAYER_LOCAL=reloaded\n")
+	writeFile(t, filepath.Join(root, ".env"), "LAYER_ENVIRONMENT=dotenv\n")
+	if err := e.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(notified) != 1 || notified[0] != "reloaded" || e.GetEnv("LAYER_LOCAL") != "reloaded" {
+		t.Fatalf("change is not reloaded but %v", notified)
+	}
+	if e.GetEnv("LAYER_DOTENV") != "" {
+		t.Fatalf("removed variable is not unset")
+	}
+}
+
+func TestNewEnvAfterPrevious(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("PROJECT_ROOT", root)
+	t.Setenv("ENVIRONMENT", "staging")
+	t.Setenv("SECRETS_DIR", t.TempDir())
+	writeFile(t, filepath.Join(root, ".env"), "EXPORTED_LAYER=first\n")
+
+	first := NewEnv()
+	defer first.Close()
+	if os.Getenv("EXPORTED_LAYER") != "first" {
+		t.Fatal("loaded value is not exported")
+	}
+
+	writeFile(t, filepath.Join(root, ".env"), "EXPORTED_LAYER=second\n")
+	second := NewEnv()
+	if value := second.GetEnv("EXPORTED_LAYER"); value != "second" {
+		t.Fatalf("value exported by the previous env is taken as set by the process, %s", value)
+	}
+	second.Close()
+	if _, ok := os.LookupEnv("EXPORTED_LAYER"); ok {
+		t.Fatal("exported value is not unset on close")
+	}
+}
+
+func testOwner() {}
+
+func TestRequirements(t *testing.T) {
+	Declare(testOwner,
+		Requirement{Name: "REQUIREMENT_DSN", Required: true, Secret: true},
+		Requirement{Name: "REQUIREMENT_PORT", Required: true, Default: "3000"},
+		Requirement{Name: "REQUIREMENT_REGION", Required: true},
+	)
+	if len(Requirements()) != len(Requirements(testOwner))-3 {
+		t.Fatalf("requirements of owners not provided are returned")
+	}
+
+	e := &Env{defaultValues: map[string]string{}}
+	requirements := Requirements(testOwner)
+	err := e.Validate(requirements)
+	if err == nil || !errors.Is(err, ErrRequired) || !strings.Contains(err.Error(), "REQUIREMENT_DSN") ||
+		!strings.Contains(err.Error(), "REQUIREMENT_REGION") || strings.Contains(err.Error(), "REQUIREMENT_PORT") {
+		t.Fatalf("missing variables are not aggregated but %v", err)
+	}
+
+	t.Setenv("REQUIREMENT_DSN", "https://key@sentry.io/1")
+	t.Setenv("REQUIREMENT_REGION", "ap-southeast-1")
+	if err := e.Validate(requirements); err != nil {
+		t.Fatal(err)
+	}
+
+	report := map[string]ConfigEntry{}
+	for _, entry := range e.Report(requirements) {
+		report[entry.Name] = entry
+	}
+	if report["REQUIREMENT_DSN"].Value != "<REDACTED>" || report["REQUIREMENT_REGION"].Value != "ap-southeast-1" ||
+		report["REQUIREMENT_REGION"].Origin != "process" || report["REQUIREMENT_PORT"].Value != "3000" {
+		t.Fatalf("report is not effective configuration but %v", report)
+	}
+}
+
+func TestSecretRef(t *testing.T) {
+	dir := t.TempDir()
+	token := filepath.Join(dir, "pulsar-token")
+	writeFile(t, token, "token\n")
+	t.Setenv("PROJECT_ROOT", t.TempDir())
+	t.Setenv("SECRETS_DIR", dir)
+	t.Setenv("SECRET_REF_TOKEN", "file://"+token)
+	t.Setenv("SECRET_REF_MISSING", "secret://unknown/path")
+
+	e := NewEnv()
+	defer e.Close()
+	if e.GetEnv("SECRET_REF_TOKEN") != "token" || !e.IsSecret("SECRET_REF_TOKEN") {
+		t.Fatalf("file reference is not resolved as secret")
+	}
+	if e.GetEnv("SECRET_REF_MISSING") != "" {
+		t.Fatalf("unresolved reference is used as value")
+	}
+	if !e.IsSecret("pulsar-token") {
+		t.Fatalf("value of secrets dir is not marked secret")
+	}
+
+	var rotated string
+	e.Subscribe("SECRET_REF_TOKEN", func(value string) {
+		rotated = value
+	})
+	writeFile(t, token, "rotated\n")
+	if err := e.RefreshSecrets(); err == nil {
+		t.Fatal("unresolved reference is not reported")
+	}
+	if rotated != "rotated" || e.GetEnv("SECRET_REF_TOKEN") != "rotated" {
+		t.Fatalf("rotated secret is not refreshed but %s", rotated)
+	}
+}
@@ -0,0 +1,171 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/shoplineapp/go-app/common"
+)
+
+// Requirement declares a variable consumed by a plugin
+type Requirement struct {
+	Name        string
+	Required    bool
+	Secret      bool
+	Description string
+	// Default is the value used by the plugin when the variable is not set
+	Default string
+}
+
+type declaration struct {
+	owner       interface{}
+	requirement Requirement
+}
+
+var (
+	declarations   []declaration
+	declarationsMu sync.RWMutex
+)
+
+// Declare registers the variables consumed by the owner, which is usually the plugin constructor,
+// so the application validates them only if the owner is provided. Nil owner applies to all applications.
+//
+//	func init() {
+//		plugins.Register(plugins.Plugin{Name: "payment", Constructors: []interface{}{NewPaymentClient}})
+//		env.Declare(NewPaymentClient, env.Requirement{Name: "PAYMENT_API_KEY", Required: true, Secret: true})
+//	}
+func Declare(owner interface{}, requirements ...Requirement) {
+	declarationsMu.Lock()
+	defer declarationsMu.Unlock()
+	for _, r := range requirements {
+		declarations = append(declarations, declaration{owner: owner, requirement: r})
+	}
+}
+
+func sameOwner(a interface{}, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Kind() == reflect.Func && vb.Kind() == reflect.Func {
+		return va.Pointer() == vb.Pointer()
+	}
+	return va.IsValid() && vb.IsValid() && va.Type() == vb.Type() && va.Type().Comparable() && a == b
+}
+
+// Requirements returns the requirements declared without owner or by any of the owners, sorted by name.
+// Requirements of the same variable are merged.
+func Requirements(owners ...interface{}) []Requirement {
+	declarationsMu.RLock()
+	defer declarationsMu.RUnlock()
+
+	merged := map[string]*Requirement{}
+	var names []string
+	for _, d := range declarations {
+		if d.owner != nil && !ownedBy(d.owner, owners) {
+			continue
+		}
+		r := d.requirement
+		existing, ok := merged[r.Name]
+		if !ok {
+			merged[r.Name] = &r
+			names = append(names, r.Name)
+			continue
+		}
+		existing.Required = existing.Required || r.Required
+		existing.Secret = existing.Secret || r.Secret
+		if existing.Description == "" {
+			existing.Description = r.Description
+		}
+		if existing.Default == "" {
+			existing.Default = r.Default
+		}
+	}
+
+	sort.Strings(names)
+	requirements := make([]Requirement, len(names))
+	for i, name := range names {
+		requirements[i] = *merged[name]
+	}
+	return requirements
+}
+
+func ownedBy(owner interface{}, owners []interface{}) bool {
+	for _, o := range owners {
+		if sameOwner(owner, o) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate returns the aggregated error of missing required variables
+func (e *Env) Validate(requirements []Requirement) error {
+	var errs []error
+	for _, r := range requirements {
+		if r.Required && r.Default == "" && e.GetEnv(r.Name) == "" {
+			errs = append(errs, fmt.Errorf("%s: %w", r.Name, ErrRequired))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ConfigEntry is the effective value of a variable, secret values are redacted
+type ConfigEntry struct {
+	Name        string
+	Value       string
+	Origin      string
+	Required    bool
+	Secret      bool
+	Description string
+}
+
+// Report returns the effective configuration of the requirements, values of secrets are redacted and
+// others are scanned by the content scanner of common.DefaultRedactor if any
+func (e *Env) Report(requirements []Requirement) []ConfigEntry {
+	entries := make([]ConfigEntry, 0, len(requirements))
+	for _, r := range requirements {
+		entry := ConfigEntry{
+			Name:        r.Name,
+			Origin:      e.Origin(r.Name),
+			Required:    r.Required,
+			Secret:      r.Secret || e.IsSecret(r.Name),
+			Description: r.Description,
+		}
+		value := e.GetEnv(r.Name)
+		if value == "" && r.Default != "" {
+			value = r.Default
+			entry.Origin = "plugin default"
+		}
+		switch {
+		case value == "":
+			entry.Origin = "unset"
+		case r.Secret || e.IsSecret(r.Name):
+			entry.Value = "<REDACTED>"
+		default:
+			entry.Value = common.DefaultRedactor.ScanString(value)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// Origin returns where the value of the variable comes from, e.g. process, the source name or default
+func (e *Env) Origin(key string) string {
+	if e.state != nil {
+		e.state.mu.Lock()
+		origin, ok := e.state.origins[key]
+		e.state.mu.Unlock()
+		if ok {
+			return origin
+		}
+	}
+	if _, ok := os.LookupEnv(key); ok {
+		return "process"
+	}
+	if _, ok := e.defaultValues[key]; ok {
+		return "default"
+	}
+	return ""
+}
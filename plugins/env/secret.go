@@ -0,0 +1,242 @@
+package env
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	secretRefScheme = "secret://"
+	fileRefScheme   = "file://"
+
+	// FileSecretProvider is the provider name of file:// references
+	FileSecretProvider = "file"
+
+	defaultSecretTTL     = 5 * time.Minute
+	secretResolveTimeout = 10 * time.Second
+)
+
+// SecretRef is a reference to a secret in the value of a variable, e.g.
+// secret://aws-sm/prod/mongo#password or file:///run/secrets/pulsar-token
+type SecretRef struct {
+	Provider string
+	Path     string
+	// Key selects a field of JSON secrets
+	Key string
+
+	raw string
+}
+
+func (r SecretRef) String() string {
+	return r.raw
+}
+
+// ParseSecretRef parses the value as a secret reference
+func ParseSecretRef(value string) (SecretRef, bool) {
+	ref := SecretRef{raw: value}
+	switch {
+	case strings.HasPrefix(value, secretRefScheme):
+		rest := strings.TrimPrefix(value, secretRefScheme)
+		provider, path, found := strings.Cut(rest, "/")
+		if !found || provider == "" || path == "" {
+			return ref, false
+		}
+		ref.Provider = provider
+		ref.Path, ref.Key, _ = strings.Cut(path, "#")
+	case strings.HasPrefix(value, fileRefScheme):
+		ref.Provider = FileSecretProvider
+		ref.Path, ref.Key, _ = strings.Cut(strings.TrimPrefix(value, fileRefScheme), "#")
+	default:
+		return ref, false
+	}
+	return ref, ref.Path != ""
+}
+
+// SecretProvider fetches secrets of references by path
+type SecretProvider interface {
+	Fetch(ctx context.Context, path string) (string, error)
+}
+
+type fileSecretProvider struct{}
+
+func (fileSecretProvider) Fetch(ctx context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+var (
+	secretProviders = map[string]SecretProvider{
+		FileSecretProvider: fileSecretProvider{},
+	}
+	secretProvidersMu sync.RWMutex
+)
+
+// RegisterSecretProvider makes the provider available to references of secret://<name>/...
+func RegisterSecretProvider(name string, provider SecretProvider) {
+	secretProvidersMu.Lock()
+	defer secretProvidersMu.Unlock()
+	secretProviders[name] = provider
+}
+
+func secretProvider(name string) (SecretProvider, bool) {
+	secretProvidersMu.RLock()
+	defer secretProvidersMu.RUnlock()
+	provider, ok := secretProviders[name]
+	return provider, ok
+}
+
+type cachedSecret struct {
+	value   string
+	expires time.Time
+}
+
+type resolvedSecret struct {
+	ref   SecretRef
+	value string
+	err   error
+}
+
+// SetSecretTTL changes how long fetched secrets are cached, secrets are refetched by the next reload once expired
+func (e *Env) SetSecretTTL(ttl time.Duration) {
+	if e.state == nil {
+		return
+	}
+	e.state.mu.Lock()
+	defer e.state.mu.Unlock()
+	e.state.secretTTL = ttl
+}
+
+// RefreshSecrets refetches all secrets regardless of the ttl, e.g. after a rotation
+func (e *Env) RefreshSecrets() error {
+	if e.state == nil {
+		return nil
+	}
+	e.state.mu.Lock()
+	e.state.secretCache = nil
+	e.state.mu.Unlock()
+	return e.Reload()
+}
+
+func (e *Env) secretTTL() time.Duration {
+	e.state.mu.Lock()
+	defer e.state.mu.Unlock()
+	if e.state.secretTTL == 0 {
+		return defaultSecretTTL
+	}
+	return e.state.secretTTL
+}
+
+func (e *Env) hasSecretRefs() bool {
+	e.state.mu.Lock()
+	defer e.state.mu.Unlock()
+	return len(e.state.secrets) > 0
+}
+
+// resolveSecrets resolves the references in values of variables, fetched secrets are cached by provider and path
+func (s *envState) resolveSecrets(values map[string]string) map[string]resolvedSecret {
+	ctx, cancel := context.WithTimeout(context.Background(), secretResolveTimeout)
+	defer cancel()
+
+	s.mu.Lock()
+	ttl := s.secretTTL
+	if ttl == 0 {
+		ttl = defaultSecretTTL
+	}
+	s.mu.Unlock()
+
+	resolved := map[string]resolvedSecret{}
+	for key, value := range values {
+		ref, ok := ParseSecretRef(value)
+		if !ok {
+			continue
+		}
+		secret, err := s.fetchSecret(ctx, ref, ttl)
+		if err == nil && ref.Key != "" {
+			secret, err = secretField(secret, ref.Key)
+		}
+		resolved[key] = resolvedSecret{ref: ref, value: secret, err: err}
+	}
+	return resolved
+}
+
+func (s *envState) fetchSecret(ctx context.Context, ref SecretRef, ttl time.Duration) (string, error) {
+	cacheKey := ref.Provider + "/" + ref.Path
+	s.mu.Lock()
+	cached, ok := s.secretCache[cacheKey]
+	s.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.value, nil
+	}
+
+	provider, found := secretProvider(ref.Provider)
+	if !found {
+		return "", fmt.Errorf("unknown secret provider %q", ref.Provider)
+	}
+	value, err := provider.Fetch(ctx, ref.Path)
+	if err != nil {
+		if ok {
+			// keep the stale secret until the provider recovers
+			return cached.value, nil
+		}
+		return "", err
+	}
+
+	s.mu.Lock()
+	if s.secretCache == nil {
+		s.secretCache = map[string]cachedSecret{}
+	}
+	s.secretCache[cacheKey] = cachedSecret{value: value, expires: time.Now().Add(ttl)}
+	s.mu.Unlock()
+	return value, nil
+}
+
+func secretField(secret string, key string) (string, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object for key %q", key)
+	}
+	value, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("key %q is not found in secret", key)
+	}
+	if str, ok := value.(string); ok {
+		return str, nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+// secretValue returns the resolved secret of the variable if its value is a reference,
+// unresolved references are empty so they are never used as the secret itself
+func (e Env) secretValue(key string, raw string) (string, bool) {
+	if e.state == nil {
+		return "", false
+	}
+	e.state.mu.Lock()
+	defer e.state.mu.Unlock()
+	secret, ok := e.state.secrets[key]
+	if !ok || secret.ref.raw != raw {
+		return "", false
+	}
+	return secret.value, true
+}
+
+// IsSecret returns whether the value of the variable is a resolved secret or loaded from a secret source
+func (e *Env) IsSecret(key string) bool {
+	if e.state == nil {
+		return false
+	}
+	e.state.mu.Lock()
+	defer e.state.mu.Unlock()
+	if _, ok := e.state.secrets[key]; ok {
+		return true
+	}
+	return e.state.secretKeys[key]
+}
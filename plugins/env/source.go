@@ -0,0 +1,135 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Source is a layer of configuration, later sources override earlier ones
+type Source interface {
+	Name() string
+	Load() (map[string]string, error)
+}
+
+type dotenvFile struct {
+	path string
+}
+
+// DotenvFile loads a .env file, missing file is empty
+func DotenvFile(path string) Source {
+	return dotenvFile{path: path}
+}
+
+func (s dotenvFile) Name() string {
+	return s.path
+}
+
+func (s dotenvFile) Load() (map[string]string, error) {
+	values, err := godotenv.Read(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return values, err
+}
+
+type configFile struct {
+	path string
+}
+
+// ConfigFile loads a YAML or JSON file, nested keys are joined by underscore in upper case,
+// e.g. log: {level: debug} is LOG_LEVEL, and lists are joined by comma
+func ConfigFile(path string) Source {
+	return configFile{path: path}
+}
+
+func (s configFile) Name() string {
+	return s.path
+}
+
+func (s configFile) Load() (map[string]string, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree map[string]interface{}
+	// JSON is a subset of YAML
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", s.path, err)
+	}
+	values := map[string]string{}
+	flatten("", tree, values)
+	return values, nil
+}
+
+func flatten(prefix string, tree map[string]interface{}, values map[string]string) {
+	for key, value := range tree {
+		key = strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flatten(key, v, values)
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(items, ",")
+		case nil:
+			values[key] = ""
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+}
+
+type secretsDir struct {
+	dir string
+}
+
+// SecretsDir loads mounted secret files, e.g. /run/secrets/DB_PASSWORD is DB_PASSWORD,
+// missing directory is empty
+func SecretsDir(dir string) Source {
+	return secretsDir{dir: dir}
+}
+
+func (s secretsDir) Name() string {
+	return s.dir
+}
+
+func (s secretsDir) Secret() bool {
+	return true
+}
+
+func (s secretsDir) Load() (map[string]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	for _, entry := range entries {
+		// e.g. ..data of kubernetes secret volumes
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		values[entry.Name()] = strings.TrimRight(string(data), "\r\n")
+	}
+	return values, nil
+}
@@ -0,0 +1,263 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultSecretsDir = "/run/secrets"
+
+// exported are values exported to the process env by any Env, so variables exported by a previous Env are
+// not mistaken for variables set by the process
+var (
+	exported   = map[string]string{}
+	exportedMu sync.Mutex
+)
+
+func export(key string, value string) {
+	exportedMu.Lock()
+	defer exportedMu.Unlock()
+	os.Setenv(key, value)
+	exported[key] = value
+}
+
+// unexport unsets the exported variable unless it is changed by others since
+func unexport(key string) {
+	exportedMu.Lock()
+	defer exportedMu.Unlock()
+	if value, ok := exported[key]; ok {
+		if current, set := os.LookupEnv(key); set && current == value {
+			os.Unsetenv(key)
+		}
+		delete(exported, key)
+	}
+}
+
+// setByProcess returns whether the variable is set by the process rather than exported by an Env
+func setByProcess(key string, value string) bool {
+	exportedMu.Lock()
+	defer exportedMu.Unlock()
+	exportedValue, ok := exported[key]
+	return !ok || exportedValue != value
+}
+
+type envState struct {
+	sources []Source
+	// variables set by the process on startup, which are never overridden by sources
+	processKeys map[string]bool
+	// values of sources exported to the process env
+	loaded map[string]string
+	// name of the source of loaded values
+	origins map[string]string
+	// variables loaded from secret sources
+	secretKeys map[string]bool
+
+	// resolved secret references by variable, and fetched secrets by provider and path
+	secrets     map[string]resolvedSecret
+	secretCache map[string]cachedSecret
+	secretTTL   time.Duration
+
+	subscribers map[int]subscriber
+	nextID      int
+	stop        chan struct{}
+
+	mu sync.Mutex
+	// serializes reloads, so subscribers are notified in order
+	reloadMu sync.Mutex
+}
+
+type subscriber struct {
+	key string
+	fn  func(value string)
+}
+
+// AddSource appends a layer overriding the existing sources and reloads
+func (e *Env) AddSource(source Source) error {
+	if e.state == nil {
+		return errors.New("env: sources are not supported, use NewEnv")
+	}
+	e.state.mu.Lock()
+	e.state.sources = append(e.state.sources, source)
+	e.state.mu.Unlock()
+	return e.Reload()
+}
+
+// secretSource is implemented by sources of secrets, e.g. SecretsDir, which values are never printed
+type secretSource interface {
+	Secret() bool
+}
+
+// Reload loads all sources, exports changed values, resolves secret references and notifies subscribers
+// of changed variables. A failed source is skipped, keeping the values of other sources.
+func (e *Env) Reload() error {
+	s := e.state
+	if s == nil {
+		return nil
+	}
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	s.mu.Lock()
+	sources := append([]Source{}, s.sources...)
+	s.mu.Unlock()
+
+	merged := map[string]string{}
+	origins := map[string]string{}
+	secretKeys := map[string]bool{}
+	var errs []error
+	for _, source := range sources {
+		values, err := source.Load()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source.Name(), err))
+			continue
+		}
+		secret := false
+		if ss, ok := source.(secretSource); ok {
+			secret = ss.Secret()
+		}
+		for key, value := range values {
+			if !s.processKeys[key] {
+				merged[key] = value
+				origins[key] = source.Name()
+				secretKeys[key] = secret
+			}
+		}
+	}
+
+	// references might be set by the process as well
+	candidates := make(map[string]string, len(merged))
+	for key, value := range merged {
+		candidates[key] = value
+	}
+	for key := range s.processKeys {
+		if value, ok := os.LookupEnv(key); ok {
+			candidates[key] = value
+		}
+	}
+	secrets := s.resolveSecrets(candidates)
+	for key, secret := range secrets {
+		if secret.err != nil {
+			errs = append(errs, fmt.Errorf("unable to resolve secret of %s: %w", key, secret.err))
+		}
+	}
+
+	s.mu.Lock()
+	changed := map[string]string{}
+	for key, value := range merged {
+		if previous, ok := s.loaded[key]; !ok || previous != value {
+			export(key, value)
+			changed[key] = value
+		}
+	}
+	for key := range s.loaded {
+		if _, ok := merged[key]; !ok {
+			unexport(key)
+			changed[key] = e.defaultValues[key]
+		}
+	}
+	for key, secret := range secrets {
+		if previous, ok := s.secrets[key]; ok && previous.value != secret.value {
+			changed[key] = secret.value
+		} else if _, ok := changed[key]; ok {
+			changed[key] = secret.value
+		}
+	}
+	s.loaded = merged
+	s.origins = origins
+	s.secretKeys = secretKeys
+	s.secrets = secrets
+	var notify []subscriber
+	for _, sub := range s.subscribers {
+		if _, ok := changed[sub.key]; ok {
+			notify = append(notify, sub)
+		}
+	}
+	s.mu.Unlock()
+
+	// subscribers might read or subscribe the env
+	for _, sub := range notify {
+		sub.fn(changed[sub.key])
+	}
+	return errors.Join(errs...)
+}
+
+// Subscribe calls fn with the new value whenever the variable is changed by a reload,
+// the returned function unsubscribes
+func (e *Env) Subscribe(key string, fn func(value string)) (unsubscribe func()) {
+	s := e.state
+	if s == nil {
+		return func() {}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subscribers == nil {
+		s.subscribers = map[int]subscriber{}
+	}
+	id := s.nextID
+	s.nextID++
+	s.subscribers[id] = subscriber{key: key, fn: fn}
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.subscribers, id)
+	}
+}
+
+// Watch reloads the sources in the interval until Close
+func (e *Env) Watch(interval time.Duration) {
+	s := e.state
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.stop != nil {
+		s.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	s.stop = stop
+	s.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := e.Reload(); err != nil {
+					log.Print("Unable to reload env ", err)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops watching the sources and unsets the values exported to the process env
+func (e *Env) Close() {
+	s := e.state
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+	s.mu.Unlock()
+
+	// a reload in progress exports values before the lock is released
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.loaded {
+		unexport(key)
+	}
+	s.loaded = nil
+}
@@ -0,0 +1,286 @@
+//go:build eventbus
+// +build eventbus
+
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shoplineapp/go-app/common"
+	"github.com/shoplineapp/go-app/plugins"
+	"github.com/shoplineapp/go-app/plugins/logger"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/fx"
+)
+
+func init() {
+	plugins.Register(plugins.Plugin{
+		Name:         "eventbus",
+		Tags:         []string{"messaging"},
+		DependsOn:    []string{"logger"},
+		Constructors: []interface{}{NewBus},
+	})
+}
+
+// Component of event bus logs, see logger.SamplingPolicy
+const LoggerComponent = "eventbus"
+
+var (
+	ErrUnregistered = errors.New("event is not registered")
+	ErrStopped      = errors.New("event bus is stopped")
+)
+
+// Metadata of the published event, see MetadataFromContext
+type Metadata struct {
+	ID          string
+	Name        string
+	PublishedAt time.Time
+}
+
+type metadataKey struct{}
+
+// MetadataFromContext returns the metadata of the event in the context of subscribers
+func MetadataFromContext(ctx context.Context) (Metadata, bool) {
+	metadata, ok := ctx.Value(metadataKey{}).(Metadata)
+	return metadata, ok
+}
+
+// Bus delivers domain events published by modules to the subscribers in the same process
+type Bus struct {
+	logger *logrus.Entry
+
+	mu          sync.RWMutex
+	names       map[reflect.Type]string
+	types       map[string]reflect.Type
+	subscribers map[reflect.Type][]*subscriber
+	stopped     bool
+	async       sync.WaitGroup
+}
+
+type subscriber struct {
+	name    string
+	handler func(ctx context.Context, event interface{}) error
+	options subscribeOptions
+}
+
+type subscribeOptions struct {
+	async    bool
+	attempts int
+	backoff  time.Duration
+	onError  func(ctx context.Context, event interface{}, err error)
+}
+
+// SubscribeOption configures the subscriber
+type SubscribeOption func(*subscribeOptions)
+
+// Async delivers events in a goroutine, with the context of the publisher without its cancellation
+func Async() SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.async = true
+	}
+}
+
+// WithRetry retries the handler up to attempts in total, with the backoff doubled after each attempt
+func WithRetry(attempts int, backoff time.Duration) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.attempts = attempts
+		o.backoff = backoff
+	}
+}
+
+// OnError handles the error of the subscriber after retries, which is not returned to the publisher then
+func OnError[E any](handle func(ctx context.Context, event E, err error)) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.onError = func(ctx context.Context, event interface{}, err error) {
+			handle(ctx, event.(E), err)
+		}
+	}
+}
+
+func NewBus(lc fx.Lifecycle, logger *logger.Logger) *Bus {
+	b := &Bus{
+		logger:      logger.Component(LoggerComponent),
+		names:       map[reflect.Type]string{},
+		types:       map[string]reflect.Type{},
+		subscribers: map[reflect.Type][]*subscriber{},
+	}
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			return b.Stop(ctx)
+		},
+	})
+	return b
+}
+
+// Register names the event type, which must be registered to be published. Registering the same type and name
+// again is allowed, e.g. by modules publishing the same event.
+func Register[E any](b *Bus, name string) error {
+	t := reflect.TypeFor[E]()
+	if name == "" {
+		return fmt.Errorf("name of event %v is required", t)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if registered, ok := b.names[t]; ok && registered != name {
+		return fmt.Errorf("event %v is registered as %s", t, registered)
+	}
+	if registered, ok := b.types[name]; ok && registered != t {
+		return fmt.Errorf("event name %s is registered by %v", name, registered)
+	}
+	b.names[t], b.types[name] = name, t
+	return nil
+}
+
+// Subscribe adds the handler of the event type, sync subscribers are called in order of subscription
+func Subscribe[E any](b *Bus, name string, handler func(ctx context.Context, event E) error, opts ...SubscribeOption) error {
+	if handler == nil {
+		return fmt.Errorf("handler of subscriber %s is required", name)
+	}
+	s := &subscriber{
+		name: name,
+		handler: func(ctx context.Context, event interface{}) error {
+			return handler(ctx, event.(E))
+		},
+		options: subscribeOptions{attempts: 1},
+	}
+	for _, opt := range opts {
+		opt(&s.options)
+	}
+	if s.options.attempts < 1 {
+		s.options.attempts = 1
+	}
+
+	t := reflect.TypeFor[E]()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, existing := range b.subscribers[t] {
+		if existing.name == name {
+			return fmt.Errorf("subscriber %s of event %v exists", name, t)
+		}
+	}
+	b.subscribers[t] = append(b.subscribers[t], s)
+	return nil
+}
+
+// Publish delivers the event to the subscribers of its type. Errors of sync subscribers without OnError are
+// returned after all of them are called, async subscribers are not waited for.
+func Publish[E any](ctx context.Context, b *Bus, event E) error {
+	t := reflect.TypeFor[E]()
+	b.mu.RLock()
+	name, registered := b.names[t]
+	subscribers := b.subscribers[t]
+	stopped := b.stopped
+	if registered && !stopped {
+		// added before Stop waits for them
+		for _, s := range subscribers {
+			if s.options.async {
+				b.async.Add(1)
+			}
+		}
+	}
+	b.mu.RUnlock()
+	if !registered {
+		return fmt.Errorf("%w: %v", ErrUnregistered, t)
+	}
+	if stopped {
+		return ErrStopped
+	}
+
+	if ctx.Value("trace_id") == nil {
+		ctx = common.NewContextWithTraceID(ctx, "")
+	}
+	ctx = context.WithValue(ctx, metadataKey{}, Metadata{ID: uuid.New().String(), Name: name, PublishedAt: time.Now()})
+
+	var errs []error
+	for _, s := range subscribers {
+		if s.options.async {
+			go func(s *subscriber) {
+				defer b.async.Done()
+				b.deliver(context.WithoutCancel(ctx), s, event)
+			}(s)
+			continue
+		}
+		if err := b.deliver(ctx, s, event); err != nil {
+			errs = append(errs, fmt.Errorf("subscriber %s: %w", s.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deliver calls the subscriber with retries, and returns the error unless it is handled by OnError
+func (b *Bus) deliver(ctx context.Context, s *subscriber, event interface{}) error {
+	metadata, _ := MetadataFromContext(ctx)
+	log := b.logger.WithFields(logrus.Fields{
+		"event":      metadata.Name,
+		"event_id":   metadata.ID,
+		"subscriber": s.name,
+		"trace_id":   common.GetTraceID(ctx),
+	})
+
+	var err error
+	backoff := s.options.backoff
+retry:
+	for attempt := 1; ; attempt++ {
+		if err = call(ctx, s, event); err == nil {
+			return nil
+		}
+		if attempt >= s.options.attempts {
+			break
+		}
+		log.WithFields(logrus.Fields{"attempt": attempt, "error": err}).Warn("Subscriber failed, retrying")
+		select {
+		case <-ctx.Done():
+			err = errors.Join(err, ctx.Err())
+			break retry
+		case <-time.After(backoff):
+			backoff *= 2
+		}
+	}
+
+	if s.options.onError != nil {
+		s.options.onError(ctx, event, err)
+		return nil
+	}
+	if s.options.async {
+		log.WithField("error", err).Error("Async subscriber failed")
+		return nil
+	}
+	return err
+}
+
+// call calls the handler with panic recovery
+func call(ctx context.Context, s *subscriber, event interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return s.handler(ctx, event)
+}
+
+// Stop rejects events published afterwards and waits for the async subscribers until ctx is done
+func (b *Bus) Stop(ctx context.Context) error {
+	b.mu.Lock()
+	b.stopped = true
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.async.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		b.logger.Warn("Event bus stopped before async subscribers finished")
+		return ctx.Err()
+	}
+}
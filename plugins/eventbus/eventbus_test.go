@@ -0,0 +1,113 @@
+//go:build eventbus
+// +build eventbus
+
+package eventbus_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shoplineapp/go-app/apptest"
+	"github.com/shoplineapp/go-app/common"
+	"github.com/shoplineapp/go-app/plugins/eventbus"
+)
+
+type orderCreated struct{ ID string }
+
+type orderCancelled struct{ ID string }
+
+func newBus(t *testing.T) *eventbus.Bus {
+	var bus *eventbus.Bus
+	apptest.New(t, apptest.WithPlugins(eventbus.NewBus)).Populate(&bus)
+	return bus
+}
+
+func TestBus(t *testing.T) {
+	bus := newBus(t)
+	if err := eventbus.Publish(context.Background(), bus, orderCreated{ID: "1"}); !errors.Is(err, eventbus.ErrUnregistered) {
+		t.Fatalf("unregistered event is published, %v", err)
+	}
+	if err := eventbus.Register[orderCreated](bus, "order.created"); err != nil {
+		t.Fatal(err)
+	}
+	if err := eventbus.Register[orderCreated](bus, "order.created"); err != nil {
+		t.Fatal("registering the same event again fails", err)
+	}
+	if err := eventbus.Register[orderCancelled](bus, "order.created"); err == nil {
+		t.Fatal("name of another event is registered")
+	}
+
+	ctx := common.NewContextWithTraceID(context.Background(), "trace")
+	received := []string{}
+	eventbus.Subscribe(bus, "first", func(ctx context.Context, e orderCreated) error {
+		metadata, _ := eventbus.MetadataFromContext(ctx)
+		received = append(received, "first "+e.ID+" "+metadata.Name+" "+common.GetTraceID(ctx))
+		return errors.New("failed")
+	})
+	var attempts atomic.Int32
+	var handled error
+	eventbus.Subscribe(bus, "retried", func(ctx context.Context, e orderCreated) error {
+		attempts.Add(1)
+		panic("boom")
+	}, eventbus.WithRetry(3, time.Millisecond), eventbus.OnError(func(ctx context.Context, e orderCreated, err error) {
+		handled = err
+	}))
+	async := make(chan string, 1)
+	eventbus.Subscribe(bus, "async", func(ctx context.Context, e orderCreated) error {
+		async <- e.ID + " " + common.GetTraceID(ctx)
+		return nil
+	}, eventbus.Async())
+	if err := eventbus.Subscribe(bus, "first", func(ctx context.Context, e orderCreated) error { return nil }); err == nil {
+		t.Fatal("subscriber of the same name is added")
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	err := eventbus.Publish(cancelled, bus, orderCreated{ID: "1"})
+	cancel()
+	if err == nil || !strings.Contains(err.Error(), "subscriber first: failed") {
+		t.Fatalf("error of the sync subscriber is not returned, %v", err)
+	}
+	if strings.Join(received, ",") != "first 1 order.created trace" {
+		t.Fatalf("sync subscriber receives %v", received)
+	}
+	if attempts.Load() != 3 || handled == nil || !strings.Contains(handled.Error(), "panic: boom") {
+		t.Fatalf("panicking subscriber is attempted %d times and handled %v", attempts.Load(), handled)
+	}
+	select {
+	case got := <-async:
+		if got != "1 trace" {
+			t.Fatalf("async subscriber receives %s", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("async subscriber is not called")
+	}
+
+	if err := bus.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := eventbus.Publish(ctx, bus, orderCreated{ID: "2"}); !errors.Is(err, eventbus.ErrStopped) {
+		t.Fatalf("event is published after stop, %v", err)
+	}
+}
+
+func TestBusStopWaitsForAsync(t *testing.T) {
+	bus := newBus(t)
+	eventbus.Register[orderCancelled](bus, "order.cancelled")
+	var done atomic.Bool
+	eventbus.Subscribe(bus, "slow", func(ctx context.Context, e orderCancelled) error {
+		time.Sleep(20 * time.Millisecond)
+		done.Store(true)
+		return nil
+	}, eventbus.Async())
+
+	if err := eventbus.Publish(context.Background(), bus, orderCancelled{ID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Stop(context.Background()); err != nil || !done.Load() {
+		t.Fatalf("stop does not wait for the async subscriber, %v", err)
+	}
+}
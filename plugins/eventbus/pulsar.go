@@ -0,0 +1,69 @@
+//go:build eventbus && pulsar
+// +build eventbus,pulsar
+
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	ap "github.com/apache/pulsar-client-go/pulsar"
+	"github.com/shoplineapp/go-app/plugins"
+	"github.com/shoplineapp/go-app/plugins/pulsar"
+	"go.uber.org/fx"
+)
+
+func init() {
+	plugins.Register(plugins.Plugin{
+		Name:         "eventbus-pulsar",
+		Tags:         []string{"messaging"},
+		DependsOn:    []string{"eventbus", "pulsar"},
+		Constructors: []interface{}{NewPulsarForwarder},
+	})
+}
+
+// PulsarForwarder forwards events to Pulsar topics with producers of PulsarProducerManager
+type PulsarForwarder struct {
+	bus       *Bus
+	producers *pulsar.PulsarProducerManager
+}
+
+// NewPulsarForwarder creates the forwarder, which stops the bus on stop before the producers are shut down, so
+// async forwards are drained while the producers are open
+func NewPulsarForwarder(lc fx.Lifecycle, bus *Bus, producers *pulsar.PulsarProducerManager) *PulsarForwarder {
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			return bus.Stop(ctx)
+		},
+	})
+	return &PulsarForwarder{bus: bus, producers: producers}
+}
+
+// ForwardToPulsar subscribes a forwarder of the events to the topic, which sends them in JSON with properties of
+// the event name, id and trace id. Subscribe options apply, e.g. Async and WithRetry. The producer is added on the
+// first event, so Pulsar is connected by then.
+func ForwardToPulsar[E any](f *PulsarForwarder, topic string, opts ...SubscribeOption) error {
+	label := fmt.Sprintf("eventbus_%s", topic)
+	return Subscribe(f.bus, fmt.Sprintf("pulsar:%s", topic), func(ctx context.Context, event E) error {
+		producer, err := f.producers.AddProducer(pulsar.WithProducerLabel(label), pulsar.WithProducerTopic(topic))
+		if err != nil {
+			return err
+		}
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		metadata, _ := MetadataFromContext(ctx)
+		properties := producer.TapTraceProperties(ctx, map[string]string{
+			"event":    metadata.Name,
+			"event_id": metadata.ID,
+		})
+		_, err = producer.Send(ctx, &ap.ProducerMessage{
+			Payload:    payload,
+			Properties: properties,
+			EventTime:  metadata.PublishedAt,
+		})
+		return err
+	}, opts...)
+}
@@ -0,0 +1,47 @@
+//go:build eventbus && pulsar
+// +build eventbus,pulsar
+
+package eventbus_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shoplineapp/go-app/apptest"
+	"github.com/shoplineapp/go-app/plugins/eventbus"
+	"github.com/shoplineapp/go-app/plugins/logger"
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxtest"
+)
+
+func TestPulsarForwarderStopsBusFirst(t *testing.T) {
+	var log *logger.Logger
+	apptest.New(t).Populate(&log)
+	var events []string
+	lc := fxtest.NewLifecycle(t)
+	bus := eventbus.NewBus(lc, log)
+	// appended like the hook of the producer manager, which is constructed before the forwarder
+	lc.Append(fx.Hook{OnStop: func(ctx context.Context) error {
+		events = append(events, "producers closed")
+		return nil
+	}})
+	eventbus.NewPulsarForwarder(lc, bus, nil)
+
+	eventbus.Register[orderCreated](bus, "order.created")
+	eventbus.Subscribe(bus, "forward", func(ctx context.Context, e orderCreated) error {
+		time.Sleep(20 * time.Millisecond)
+		events = append(events, "forwarded")
+		return nil
+	}, eventbus.Async())
+
+	lc.RequireStart()
+	if err := eventbus.Publish(context.Background(), bus, orderCreated{ID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	lc.RequireStop()
+	if strings.Join(events, ", ") != "forwarded, producers closed" {
+		t.Fatalf("producers are closed before async forwards are drained: %v", events)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build grpc
+// +build grpc
+
+package grpc
+
+import "github.com/shoplineapp/go-app/plugins/env"
+
+// Config of the GRPC server
+type Config struct {
+	Port string `env:"GRPC_SERVER_PORT" default:"3000"`
+}
+
+// LoadConfig binds the config from env, fields failed to bind are reported in the error with defaults in place
+func LoadConfig(e *env.Env) (Config, error) {
+	config := Config{Port: "3000"}
+	err := e.Bind(&config)
+	return config, err
+}
@@ -17,7 +17,13 @@ import (
 )
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewGrpcServer)
+	plugins.Register(plugins.Plugin{
+		Name:         "grpc",
+		Tags:         []string{"grpc", "server"},
+		DependsOn:    []string{"env", "logger"},
+		Constructors: []interface{}{NewGrpcServer},
+	})
+	env.Declare(NewGrpcServer, env.Requirement{Name: "GRPC_SERVER_PORT", Default: "3000", Description: "Port of the GRPC server"})
 }
 
 type GrpcServer struct {
@@ -39,11 +45,11 @@ func (g GrpcServer) Server() *grpc.Server {
 
 func (g GrpcServer) Serve() {
 	if g.listener == nil {
-		var port string = g.env.GetEnv("GRPC_SERVER_PORT")
-
-		if len(port) == 0 {
-			port = "3000"
+		config, err := LoadConfig(g.env)
+		if err != nil {
+			g.logger.WithField("error", err).Warn("Invalid GRPC server configuration, fallback to default")
 		}
+		port := config.Port
 		lis, err := net.Listen("tcp", fmt.Sprintf(":%s", port))
 		if err != nil {
 			g.logger.WithFields(logrus.Fields{"port": port, "error": err}).Error("Unable to listen to port")
@@ -68,6 +74,11 @@ func (g *GrpcServer) Shutdown() {
 	g.logger.Info("Bye.")
 }
 
+// Config returns the server config bound from env
+func (g GrpcServer) Config() (Config, error) {
+	return LoadConfig(g.env)
+}
+
 func (g *GrpcServer) Configure(opt ...grpc.ServerOption) {
 	opt = append(globalServerOptions, opt...)
 	grpc := grpc.NewServer(opt...)
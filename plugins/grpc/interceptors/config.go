@@ -0,0 +1,21 @@
+//go:build grpc
+// +build grpc
+
+package interceptors
+
+import (
+	"time"
+
+	"github.com/shoplineapp/go-app/plugins/env"
+)
+
+// DeadlineConfig of the deadline interceptor, the timeout is either a duration or a number of seconds
+type DeadlineConfig struct {
+	DefaultTimeout time.Duration `env:"GRPC_HANDLER_DEFAULT_TIMEOUT" default:"30s" unit:"s"`
+}
+
+func LoadDeadlineConfig(e *env.Env) (DeadlineConfig, error) {
+	config := DeadlineConfig{DefaultTimeout: 30 * time.Second}
+	err := e.Bind(&config)
+	return config, err
+}
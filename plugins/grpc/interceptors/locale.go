@@ -12,7 +12,11 @@ import (
 )
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewLocaleInterceptor)
+	plugins.Register(plugins.Plugin{
+		Name:         "grpc-locale",
+		Tags:         []string{"grpc", "interceptor"},
+		Constructors: []interface{}{NewLocaleInterceptor},
+	})
 }
 
 type LocaleInterceptor struct {
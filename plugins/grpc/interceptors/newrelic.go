@@ -23,7 +23,12 @@ import (
 )
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewNewrelicInterceptor)
+	plugins.Register(plugins.Plugin{
+		Name:         "grpc-newrelic",
+		Tags:         []string{"grpc", "interceptor"},
+		DependsOn:    []string{"newrelic"},
+		Constructors: []interface{}{NewNewrelicInterceptor},
+	})
 }
 
 type NewrelicInterceptor struct {
@@ -19,7 +19,12 @@ import (
 )
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewOtelInterceptor)
+	plugins.Register(plugins.Plugin{
+		Name:         "grpc-otel",
+		Tags:         []string{"grpc", "interceptor"},
+		DependsOn:    []string{"otel"},
+		Constructors: []interface{}{NewOtelInterceptor},
+	})
 }
 
 type OtelInterceptor struct {
@@ -14,7 +14,11 @@ import (
 )
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewGrpcErrorRecoveryInterceptor)
+	plugins.Register(plugins.Plugin{
+		Name:         "grpc-recovery",
+		Tags:         []string{"grpc", "interceptor"},
+		Constructors: []interface{}{NewGrpcErrorRecoveryInterceptor},
+	})
 }
 
 type StackTracer interface {
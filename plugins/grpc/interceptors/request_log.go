@@ -25,7 +25,12 @@ var (
 
 func init() {
 	redactor = common.DefaultRedactor
-	plugins.Registry = append(plugins.Registry, NewGrpcRequestLogInterceptor)
+	plugins.Register(plugins.Plugin{
+		Name:         "grpc-request-log",
+		Tags:         []string{"grpc", "interceptor"},
+		DependsOn:    []string{"env", "logger"},
+		Constructors: []interface{}{NewGrpcRequestLogInterceptor},
+	})
 }
 
 func SetRedactor(r *common.Redactor) {
@@ -143,7 +148,7 @@ func (i RequestLogInterceptor) Handler() grpc.UnaryServerInterceptor {
 		stop := time.Now()
 
 		resLogger := log.WithFields(
-			logrus.Fields{"res_time": stop.Sub(start).String(), "req": redactor.Redact(req)},
+			logrus.Fields{"res_time": stop.Sub(start).String(), "req": logger.Unredacted(redactor.Redact(req))},
 		)
 
 		if err != nil || res == nil {
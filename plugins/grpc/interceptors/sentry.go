@@ -22,7 +22,12 @@ import (
 )
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewSentryInterceptor)
+	plugins.Register(plugins.Plugin{
+		Name:         "grpc-sentry",
+		Tags:         []string{"grpc", "interceptor"},
+		DependsOn:    []string{"sentry"},
+		Constructors: []interface{}{NewSentryInterceptor},
+	})
 }
 
 type SentryInterceptor struct {
@@ -5,31 +5,44 @@ package interceptors
 
 import (
 	"context"
-	"strconv"
-	"time"
 
 	"github.com/shoplineapp/go-app/plugins"
 	"github.com/shoplineapp/go-app/plugins/env"
+	"github.com/shoplineapp/go-app/plugins/logger"
+	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewGrpcDeadlineInterceptor)
+	plugins.Register(plugins.Plugin{
+		Name:         "grpc-deadline",
+		Tags:         []string{"grpc", "interceptor"},
+		DependsOn:    []string{"env", "logger"},
+		Constructors: []interface{}{ProvideGrpcDeadlineInterceptor},
+	})
+	env.Declare(ProvideGrpcDeadlineInterceptor, env.Requirement{Name: "GRPC_HANDLER_DEFAULT_TIMEOUT", Default: "30s", Description: "Timeout of GRPC handlers, in seconds or a duration"})
 }
 
 type DeadlineInterceptor struct {
-	env *env.Env
+	env    *env.Env
+	logger *logger.Logger
 }
 
 func (h DeadlineInterceptor) Handler() grpc.UnaryServerInterceptor {
-	timeout, pErr := strconv.ParseInt(h.env.GetEnv("GRPC_HANDLER_DEFAULT_TIMEOUT"), 10, 64)
-	if pErr != nil {
-		timeout = 30
+	// fallback to the default timeout on invalid value
+	config, err := LoadDeadlineConfig(h.env)
+	if err != nil {
+		log := logrus.StandardLogger().WithField("error", err)
+		if h.logger != nil {
+			log = h.logger.WithField("error", err)
+		}
+		log.Warn("Invalid GRPC deadline configuration, fallback to default")
 	}
+	timeout := config.DefaultTimeout
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		innerCtx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+		innerCtx, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
 
 		resCh := make(chan interface{}, 1)
@@ -54,6 +67,12 @@ func (h DeadlineInterceptor) Handler() grpc.UnaryServerInterceptor {
 	}
 }
 
+// NewGrpcDeadlineInterceptor creates the interceptor reporting invalid configuration to the standard logger
 func NewGrpcDeadlineInterceptor(env *env.Env) *DeadlineInterceptor {
 	return &DeadlineInterceptor{env: env}
 }
+
+// ProvideGrpcDeadlineInterceptor creates the interceptor reporting invalid configuration to the logger
+func ProvideGrpcDeadlineInterceptor(env *env.Env, logger *logger.Logger) *DeadlineInterceptor {
+	return &DeadlineInterceptor{env: env, logger: logger}
+}
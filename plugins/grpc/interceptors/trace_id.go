@@ -13,7 +13,11 @@ import (
 )
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewTraceIdInterceptor)
+	plugins.Register(plugins.Plugin{
+		Name:         "grpc-trace-id",
+		Tags:         []string{"grpc", "interceptor"},
+		Constructors: []interface{}{NewTraceIdInterceptor},
+	})
 }
 
 type TraceIdInterceptor struct {
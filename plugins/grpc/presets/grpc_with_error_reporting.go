@@ -19,7 +19,12 @@ import (
 )
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewDefaultGrpcServerWithErrorReporting)
+	plugins.Register(plugins.Plugin{
+		Name:         "grpc-error-reporting-preset",
+		Tags:         []string{"grpc", "preset"},
+		DependsOn:    []string{"grpc", "grpc-deadline", "grpc-trace-id", "grpc-locale", "grpc-request-log", "grpc-recovery", "grpc-sentry", "grpc-newrelic", "grpc-otel"},
+		Constructors: []interface{}{NewDefaultGrpcServerWithErrorReporting},
+	})
 }
 
 type DefaultGrpcServerWithErrorReporting struct {
@@ -19,7 +19,12 @@ import (
 )
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewDefaultGrpcServerWithNewrelic)
+	plugins.Register(plugins.Plugin{
+		Name:         "grpc-newrelic-preset",
+		Tags:         []string{"grpc", "preset"},
+		DependsOn:    []string{"grpc", "grpc-deadline", "grpc-trace-id", "grpc-locale", "grpc-request-log", "grpc-recovery", "grpc-newrelic", "grpc-otel"},
+		Constructors: []interface{}{NewDefaultGrpcServerWithNewrelic},
+	})
 }
 
 type DefaultGrpcServerWithNewrelic struct {
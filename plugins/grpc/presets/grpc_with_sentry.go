@@ -19,7 +19,12 @@ import (
 )
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewDefaultGrpcServerWithSentry)
+	plugins.Register(plugins.Plugin{
+		Name:         "grpc-sentry-preset",
+		Tags:         []string{"grpc", "preset"},
+		DependsOn:    []string{"grpc", "grpc-deadline", "grpc-trace-id", "grpc-locale", "grpc-request-log", "grpc-recovery", "grpc-sentry", "grpc-otel"},
+		Constructors: []interface{}{NewDefaultGrpcServerWithSentry},
+	})
 }
 
 type DefaultGrpcServerWithSentry struct {
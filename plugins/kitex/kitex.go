@@ -22,7 +22,12 @@ import (
 )
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewKitexServer)
+	plugins.Register(plugins.Plugin{
+		Name:         "kitex",
+		Tags:         []string{"kitex", "server"},
+		DependsOn:    []string{"env", "logger", "kitex-trace-id", "kitex-request-log", "kitex-deadline"},
+		Constructors: []interface{}{NewKitexServer},
+	})
 }
 
 type KitexServer struct {
@@ -16,7 +16,12 @@ import (
 )
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewKitexNewrelicMiddleware)
+	plugins.Register(plugins.Plugin{
+		Name:         "kitex-newrelic",
+		Tags:         []string{"kitex", "middleware"},
+		DependsOn:    []string{"newrelic"},
+		Constructors: []interface{}{NewKitexNewrelicMiddleware},
+	})
 }
 
 type KitexNewrelicMiddleware struct {
@@ -12,7 +12,12 @@ import (
 )
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewKitexRequestLogMiddleware)
+	plugins.Register(plugins.Plugin{
+		Name:         "kitex-request-log",
+		Tags:         []string{"kitex", "middleware"},
+		DependsOn:    []string{"logger"},
+		Constructors: []interface{}{NewKitexRequestLogMiddleware},
+	})
 }
 
 type KitexRequestLogMiddleware struct {
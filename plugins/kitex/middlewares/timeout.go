@@ -13,7 +13,12 @@ import (
 )
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewKitexDeadlineMiddleware)
+	plugins.Register(plugins.Plugin{
+		Name:         "kitex-deadline",
+		Tags:         []string{"kitex", "middleware"},
+		DependsOn:    []string{"env"},
+		Constructors: []interface{}{NewKitexDeadlineMiddleware},
+	})
 }
 
 type KitexDeadlineMiddleware struct {
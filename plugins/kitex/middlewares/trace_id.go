@@ -11,7 +11,11 @@ import (
 )
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewKitexTraceIDMiddleware)
+	plugins.Register(plugins.Plugin{
+		Name:         "kitex-trace-id",
+		Tags:         []string{"kitex", "middleware"},
+		Constructors: []interface{}{NewKitexTraceIDMiddleware},
+	})
 }
 
 type KitexTraceIDMiddleware struct {
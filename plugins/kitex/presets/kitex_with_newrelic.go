@@ -14,7 +14,12 @@ import (
 )
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewDefaultKitexServerWithNewrelic)
+	plugins.Register(plugins.Plugin{
+		Name:         "kitex-newrelic-preset",
+		Tags:         []string{"kitex", "preset"},
+		DependsOn:    []string{"kitex", "kitex-newrelic"},
+		Constructors: []interface{}{NewDefaultKitexServerWithNewrelic},
+	})
 }
 
 type DefaultKitexServerWithNewrelic struct {
@@ -0,0 +1,318 @@
+//go:build leader
+// +build leader
+
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shoplineapp/go-app/common"
+	"github.com/shoplineapp/go-app/plugins"
+	"github.com/shoplineapp/go-app/plugins/env"
+	"github.com/shoplineapp/go-app/plugins/logger"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/fx"
+)
+
+func init() {
+	plugins.Register(plugins.Plugin{
+		Name:         "leader",
+		Tags:         []string{"worker"},
+		DependsOn:    []string{"env", "logger"},
+		Constructors: []interface{}{NewElector},
+	})
+	env.Declare(NewElector,
+		env.Requirement{Name: "LEADER_ELECTION_NAME", Description: "Name of the lease, the replicas of the same name elect one leader, APP_NAME by default"},
+		env.Requirement{Name: "LEADER_LEASE_TTL", Default: "15s", Description: "Duration of the lease, renewed every third of it"},
+	)
+}
+
+// Component of leader election logs, see logger.SamplingPolicy
+const LoggerComponent = "leader"
+
+// Lease is held by the leader until it expires
+type Lease struct {
+	Name   string
+	Holder string
+	// Token increases whenever the holder changes, writes of the leader are fenced by it
+	Token     int64
+	ExpiresAt time.Time
+}
+
+// LeaseStore keeps leases for the replicas, e.g. MongoLeaseStore
+type LeaseStore interface {
+	// Acquire takes the lease if it is free, expired or held by the holder, and extends it by the ttl
+	Acquire(ctx context.Context, name string, holder string, ttl time.Duration) (Lease, bool, error)
+	// Release expires the lease if it is held by the holder
+	Release(ctx context.Context, name string, holder string) error
+}
+
+// Config of the election
+type Config struct {
+	Name    string        `env:"LEADER_ELECTION_NAME"`
+	AppName string        `env:"APP_NAME"`
+	TTL     time.Duration `env:"LEADER_LEASE_TTL" default:"15s"`
+}
+
+func LoadConfig(e *env.Env) (Config, error) {
+	var config Config
+	err := e.Bind(&config)
+	if config.Name == "" {
+		config.Name = config.AppName
+	}
+	if config.Name == "" {
+		config.Name = "go-app"
+	}
+	if config.TTL <= 0 {
+		err = errors.Join(err, fmt.Errorf("LEADER_LEASE_TTL must be positive, got %v", config.TTL))
+	}
+	return config, err
+}
+
+type tokenKey struct{}
+
+// TokenFromContext returns the fencing token of the leadership of the context, e.g. the one of OnElected
+func TokenFromContext(ctx context.Context) (int64, bool) {
+	token, ok := ctx.Value(tokenKey{}).(int64)
+	return token, ok
+}
+
+// Elector elects one leader among the replicas by renewing a lease of the store
+type Elector struct {
+	logger *logrus.Entry
+	store  LeaseStore
+	config Config
+	id     string
+
+	mu        sync.Mutex
+	leading   context.Context
+	stepDown  context.CancelFunc
+	token     int64
+	renewedAt time.Time
+	expiry    *time.Timer
+	elected   []func(ctx context.Context)
+	lost      []func()
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+type ElectorParams struct {
+	fx.In
+
+	Lifecycle fx.Lifecycle
+	Logger    *logger.Logger
+	Env       *env.Env
+	Store     LeaseStore
+}
+
+func NewElector(params ElectorParams) (*Elector, error) {
+	config, err := LoadConfig(params.Env)
+	if err != nil {
+		return nil, err
+	}
+	e := &Elector{
+		logger: params.Logger.Component(LoggerComponent).WithField("election", config.Name),
+		store:  params.Store,
+		config: config,
+		id:     fmt.Sprintf("%s-%s", common.GetHostname(), uuid.New().String()[:8]),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	params.Lifecycle.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			e.Start()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return e.Stop(ctx)
+		},
+	})
+	return e, nil
+}
+
+// ID is the holder of the lease when the replica is the leader
+func (e *Elector) ID() string {
+	return e.id
+}
+
+// IsLeader returns whether the replica holds the lease
+func (e *Elector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leading != nil
+}
+
+// Token returns the fencing token of the leadership, or 0 if it is not the leader
+func (e *Elector) Token() int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.leading == nil {
+		return 0
+	}
+	return e.token
+}
+
+// Lead returns a context of ctx, which carries the fencing token and is cancelled when the leadership is lost,
+// or false if it is not the leader. ctx must be cancelled to release the context.
+func (e *Elector) Lead(ctx context.Context) (context.Context, bool) {
+	e.mu.Lock()
+	leading, token := e.leading, e.token
+	e.mu.Unlock()
+	if leading == nil || leading.Err() != nil {
+		return ctx, false
+	}
+	ctx, cancel := context.WithCancel(context.WithValue(ctx, tokenKey{}, token))
+	stop := context.AfterFunc(leading, cancel)
+	context.AfterFunc(ctx, func() { stop() })
+	return ctx, true
+}
+
+// OnElected runs fn in a goroutine whenever the replica becomes the leader, with a context cancelled when the
+// leadership is lost, so leader only components run until the context is done
+func (e *Elector) OnElected(fn func(ctx context.Context)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.elected = append(e.elected, fn)
+	if e.leading != nil {
+		go fn(e.leading)
+	}
+}
+
+// OnLost calls fn whenever the leadership is lost, including on stop
+func (e *Elector) OnLost(fn func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lost = append(e.lost, fn)
+}
+
+// Start campaigns for the lease and renews it every third of the ttl
+func (e *Elector) Start() {
+	e.logger.WithFields(logrus.Fields{"holder": e.id, "ttl": e.config.TTL.String()}).Info("Leader election started")
+	go e.loop()
+}
+
+// Stop stops renewing, steps down and releases the lease if it is the leader
+func (e *Elector) Stop(ctx context.Context) error {
+	select {
+	case <-e.stop:
+		return nil
+	default:
+	}
+	close(e.stop)
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if !e.IsLeader() {
+		return nil
+	}
+	e.stepDownWith("Leader stepped down on stop")
+	return e.store.Release(ctx, e.config.Name, e.id)
+}
+
+func (e *Elector) loop() {
+	defer close(e.done)
+	interval := e.config.TTL / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		e.campaign(interval)
+		select {
+		case <-e.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// campaign acquires or renews the lease. The lease is counted from the attempt, as the store may extend it any
+// time before returning, and the leader steps down by a timer if it is not renewed before the last interval of
+// the ttl, even if the store blocks.
+func (e *Elector) campaign(timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	attempted := time.Now()
+	lease, acquired, err := e.store.Acquire(ctx, e.config.Name, e.id, e.config.TTL)
+
+	switch {
+	case err != nil:
+		e.logger.WithField("error", err).Warn("Unable to renew the lease")
+	case acquired:
+		e.elect(lease, attempted, e.config.TTL-timeout)
+	case e.IsLeader():
+		e.stepDownWith(fmt.Sprintf("Leadership is lost to %s", lease.Holder))
+	}
+}
+
+func (e *Elector) elect(lease Lease, renewedAt time.Time, validity time.Duration) {
+	remaining := time.Until(renewedAt.Add(validity))
+	if remaining <= 0 {
+		// renewed too late to lead, the timer steps down the leader
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.renewedAt = renewedAt
+	if e.expiry != nil {
+		e.expiry.Stop()
+	}
+	e.expiry = time.AfterFunc(remaining, func() { e.expire(renewedAt) })
+	if e.leading != nil && e.token == lease.Token {
+		return
+	}
+	if e.stepDown != nil {
+		e.stepDown()
+	}
+	e.token = lease.Token
+	e.leading, e.stepDown = context.WithCancel(context.WithValue(context.Background(), tokenKey{}, lease.Token))
+	e.logger.WithField("token", lease.Token).Info("Elected as the leader")
+	for _, fn := range e.elected {
+		go fn(e.leading)
+	}
+}
+
+// expire steps down if the lease is not renewed since renewedAt
+func (e *Elector) expire(renewedAt time.Time) {
+	e.mu.Lock()
+	var lost []func()
+	if e.renewedAt.Equal(renewedAt) {
+		lost = e.resign("Leadership is lost, the lease is not renewed before it expires")
+	}
+	e.mu.Unlock()
+
+	for _, fn := range lost {
+		fn()
+	}
+}
+
+func (e *Elector) stepDownWith(message string) {
+	e.mu.Lock()
+	lost := e.resign(message)
+	e.mu.Unlock()
+
+	for _, fn := range lost {
+		fn()
+	}
+}
+
+// resign cancels the leadership and returns the callbacks of the loss, which must be called without the lock
+func (e *Elector) resign(message string) []func() {
+	if e.expiry != nil {
+		e.expiry.Stop()
+		e.expiry = nil
+	}
+	if e.leading == nil {
+		return nil
+	}
+	e.stepDown()
+	e.leading, e.stepDown = nil, nil
+	e.logger.WithField("token", e.token).Warn(message)
+	return append([]func(){}, e.lost...)
+}
@@ -0,0 +1,127 @@
+//go:build leader
+// +build leader
+
+package leader_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shoplineapp/go-app/apptest"
+	"github.com/shoplineapp/go-app/plugins/leader"
+)
+
+func newElector(t *testing.T, store leader.LeaseStore) *leader.Elector {
+	var e *leader.Elector
+	a := apptest.New(t,
+		apptest.WithPlugins(leader.NewElector, func() leader.LeaseStore { return store }),
+		apptest.WithEnv(map[string]string{"LEADER_ELECTION_NAME": "test", "LEADER_LEASE_TTL": "60ms"}),
+	)
+	a.Populate(&e)
+	return e
+}
+
+func eventually(t *testing.T, condition func() bool, message string) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if condition() {
+			return
+		}
+	}
+	t.Fatal(message)
+}
+
+func TestElector(t *testing.T) {
+	store := leader.NewMemoryLeaseStore()
+	first := newElector(t, store)
+	eventually(t, first.IsLeader, "first replica is not elected")
+
+	var elected, lost atomic.Int32
+	second := newElector(t, store)
+	second.OnElected(func(ctx context.Context) {
+		if token, _ := leader.TokenFromContext(ctx); token == 2 {
+			elected.Add(1)
+		}
+	})
+	second.OnLost(func() { lost.Add(1) })
+	time.Sleep(100 * time.Millisecond)
+	if second.IsLeader() || first.Token() != 1 {
+		t.Fatalf("second replica is elected while the first renews the lease of token %d", first.Token())
+	}
+
+	leading, ok := first.Lead(context.Background())
+	if !ok {
+		t.Fatal("leader does not lead")
+	}
+	if token, _ := leader.TokenFromContext(leading); token != 1 {
+		t.Fatalf("token of the leading context is %d", token)
+	}
+	if _, ok := second.Lead(context.Background()); ok {
+		t.Fatal("follower leads")
+	}
+
+	if err := first.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() bool { return leading.Err() != nil && !first.IsLeader() }, "leading context is not cancelled on stop")
+	eventually(t, second.IsLeader, "second replica is not elected after the first released the lease")
+	eventually(t, func() bool { return elected.Load() == 1 }, "elected callback is not called with the fencing token")
+	if second.Token() != 2 {
+		t.Fatalf("token of the new leader is %d", second.Token())
+	}
+
+	if err := second.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if lost.Load() != 1 {
+		t.Fatal("lost callback is not called on stop")
+	}
+}
+
+type failingStore struct {
+	leader.LeaseStore
+	failing atomic.Bool
+}
+
+func (s *failingStore) Acquire(ctx context.Context, name string, holder string, ttl time.Duration) (leader.Lease, bool, error) {
+	if s.failing.Load() {
+		return leader.Lease{}, false, context.DeadlineExceeded
+	}
+	return s.LeaseStore.Acquire(ctx, name, holder, ttl)
+}
+
+func TestElectorStepDown(t *testing.T) {
+	store := &failingStore{LeaseStore: leader.NewMemoryLeaseStore()}
+	e := newElector(t, store)
+	eventually(t, e.IsLeader, "replica is not elected")
+
+	var lost atomic.Int32
+	e.OnLost(func() { lost.Add(1) })
+	store.failing.Store(true)
+	eventually(t, func() bool { return !e.IsLeader() && lost.Load() == 1 }, "leader does not step down when the lease is not renewed")
+}
+
+type blockingStore struct {
+	leader.LeaseStore
+	blocking atomic.Bool
+	release  chan struct{}
+}
+
+func (s *blockingStore) Acquire(ctx context.Context, name string, holder string, ttl time.Duration) (leader.Lease, bool, error) {
+	if s.blocking.Load() {
+		<-s.release
+	}
+	return s.LeaseStore.Acquire(ctx, name, holder, ttl)
+}
+
+func TestElectorStepDownBlocked(t *testing.T) {
+	store := &blockingStore{LeaseStore: leader.NewMemoryLeaseStore(), release: make(chan struct{})}
+	e := newElector(t, store)
+	t.Cleanup(func() { close(store.release) })
+	eventually(t, e.IsLeader, "replica is not elected")
+
+	store.blocking.Store(true)
+	eventually(t, func() bool { return !e.IsLeader() }, "leader does not step down while the store blocks the renewal")
+}
@@ -0,0 +1,46 @@
+//go:build leader
+// +build leader
+
+package leader
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryLeaseStore keeps leases in memory, for tests and replicas in the same process
+type MemoryLeaseStore struct {
+	mu     sync.Mutex
+	leases map[string]Lease
+}
+
+func NewMemoryLeaseStore() *MemoryLeaseStore {
+	return &MemoryLeaseStore{leases: map[string]Lease{}}
+}
+
+func (s *MemoryLeaseStore) Acquire(ctx context.Context, name string, holder string, ttl time.Duration) (Lease, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	lease := s.leases[name]
+	if lease.Holder != holder && now.Before(lease.ExpiresAt) {
+		return lease, false, nil
+	}
+	if lease.Holder != holder {
+		lease.Token++
+	}
+	lease.Name, lease.Holder, lease.ExpiresAt = name, holder, now.Add(ttl)
+	s.leases[name] = lease
+	return lease, true, nil
+}
+
+func (s *MemoryLeaseStore) Release(ctx context.Context, name string, holder string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if lease, ok := s.leases[name]; ok && lease.Holder == holder {
+		lease.ExpiresAt = time.Now()
+		s.leases[name] = lease
+	}
+	return nil
+}
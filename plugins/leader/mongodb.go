@@ -0,0 +1,86 @@
+//go:build leader && mongodb
+// +build leader,mongodb
+
+package leader
+
+import (
+	"context"
+	"time"
+
+	"github.com/shoplineapp/go-app/plugins"
+	"github.com/shoplineapp/go-app/plugins/mongodb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	plugins.Register(plugins.Plugin{
+		Name:         "leader-mongodb",
+		Tags:         []string{"worker"},
+		DependsOn:    []string{"mongodb"},
+		Constructors: []interface{}{NewMongoLeaseStore},
+	})
+}
+
+// LeaseCollection is the collection of leases in MongoStore
+var LeaseCollection = "leader_leases"
+
+// MongoLeaseStore keeps leases in MongoStore, expiry is compared by the clock of the server with $$NOW
+type MongoLeaseStore struct {
+	store *mongodb.MongoStore
+}
+
+type mongoLease struct {
+	Name      string    `bson:"_id"`
+	Holder    string    `bson:"holder"`
+	Token     int64     `bson:"token"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// NewMongoLeaseStore provides the LeaseStore of the elector, the store must be connected before start
+func NewMongoLeaseStore(store *mongodb.MongoStore) LeaseStore {
+	return &MongoLeaseStore{store: store}
+}
+
+func (s *MongoLeaseStore) Acquire(ctx context.Context, name string, holder string, ttl time.Duration) (Lease, bool, error) {
+	filter := bson.M{"_id": name, "$or": bson.A{
+		bson.M{"holder": holder},
+		bson.M{"$expr": bson.M{"$lte": bson.A{"$expires_at", "$$NOW"}}},
+	}}
+	// the token increases when the holder changes
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"token": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{"$holder", holder}},
+			"$token",
+			bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$token", 0}}, 1}},
+		}},
+		"holder":     holder,
+		"expires_at": bson.M{"$add": bson.A{"$$NOW", ttl.Milliseconds()}},
+	}}}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var acquired mongoLease
+	err := s.store.Collection(LeaseCollection).FindOneAndUpdate(ctx, filter, update, opts).Decode(&acquired)
+	if err == nil {
+		return Lease(acquired), true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return Lease{}, false, err
+	}
+
+	// held by another replica, the upsert conflicts with it
+	var held mongoLease
+	if err := s.store.Collection(LeaseCollection).FindOne(ctx, bson.M{"_id": name}).Decode(&held); err != nil {
+		return Lease{}, false, err
+	}
+	return Lease(held), false, nil
+}
+
+func (s *MongoLeaseStore) Release(ctx context.Context, name string, holder string) error {
+	_, err := s.store.Collection(LeaseCollection).UpdateOne(ctx,
+		bson.M{"_id": name, "holder": holder},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"expires_at": "$$NOW"}}}},
+	)
+	return err
+}
@@ -0,0 +1,178 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	joonix "github.com/joonix/log"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+	FormatGCP  = "gcp"
+	FormatECS  = "ecs"
+	FormatOtel = "otel"
+
+	ecsVersion = "8.11.0"
+)
+
+// NewFormatter returns the formatter of given LOG_FORMAT value
+func NewFormatter(format string, serviceName string) (logrus.Formatter, error) {
+	switch strings.ToLower(format) {
+	case FormatText, "":
+		return new(logrus.TextFormatter), nil
+	case FormatJSON:
+		return &logrus.JSONFormatter{}, nil
+	case FormatGCP:
+		return joonix.NewFormatter(), nil
+	case FormatECS:
+		return &ECSFormatter{ServiceName: serviceName}, nil
+	case FormatOtel:
+		return &OtelFormatter{ServiceName: serviceName}, nil
+	}
+	return nil, fmt.Errorf("unsupported log format %q", format)
+}
+
+// fieldValue makes errors serializable, json.Marshal renders them as {}
+func fieldValue(value interface{}) interface{} {
+	if err, ok := value.(error); ok {
+		return err.Error()
+	}
+	return value
+}
+
+func writeJSON(data map[string]interface{}) ([]byte, error) {
+	b := &bytes.Buffer{}
+	encoder := json.NewEncoder(b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(data); err != nil {
+		return nil, fmt.Errorf("failed to marshal fields to JSON, %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+// ECSFormatter formats entries following the Elastic Common Schema
+// https://www.elastic.co/guide/en/ecs/current/ecs-field-reference.html
+type ECSFormatter struct {
+	ServiceName string
+}
+
+func (f *ECSFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	data := make(map[string]interface{}, len(entry.Data)+8)
+	labels := make(map[string]interface{}, len(entry.Data))
+	for key, value := range entry.Data {
+		switch key {
+		case "trace_id":
+			data["trace.id"] = value
+		case "error", logrus.ErrorKey:
+			data["error.message"] = fieldValue(value)
+		default:
+			labels[key] = fieldValue(value)
+		}
+	}
+	if len(labels) > 0 {
+		data["labels"] = labels
+	}
+
+	data["@timestamp"] = entry.Time.UTC().Format(time.RFC3339Nano)
+	data["log.level"] = entry.Level.String()
+	data["message"] = entry.Message
+	data["ecs.version"] = ecsVersion
+	if f.ServiceName != "" {
+		data["service.name"] = f.ServiceName
+	}
+	if entry.HasCaller() {
+		data["log.origin.function"] = entry.Caller.Function
+		data["log.origin.file.name"] = entry.Caller.File
+		data["log.origin.file.line"] = entry.Caller.Line
+	}
+
+	return writeJSON(data)
+}
+
+// OtelFormatter formats entries following the OpenTelemetry log data model
+// https://opentelemetry.io/docs/specs/otel/logs/data-model/
+type OtelFormatter struct {
+	ServiceName string
+}
+
+func otelSeverityNumber(level logrus.Level) int {
+	switch level {
+	case logrus.TraceLevel:
+		return 1
+	case logrus.DebugLevel:
+		return 5
+	case logrus.InfoLevel:
+		return 9
+	case logrus.WarnLevel:
+		return 13
+	case logrus.ErrorLevel:
+		return 17
+	case logrus.FatalLevel:
+		return 21
+	}
+	return 24
+}
+
+// otelTraceID normalizes trace ids such as UUIDs to 32 lowercase hex digits
+func otelTraceID(value interface{}) (string, bool) {
+	str, ok := value.(string)
+	if !ok {
+		return "", false
+	}
+	traceID, err := trace.TraceIDFromHex(strings.ToLower(strings.ReplaceAll(str, "-", "")))
+	if err != nil {
+		return "", false
+	}
+	return traceID.String(), true
+}
+
+func (f *OtelFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	attributes := make(map[string]interface{}, len(entry.Data)+3)
+	data := map[string]interface{}{
+		"Timestamp":      strconv.FormatInt(entry.Time.UnixNano(), 10),
+		"SeverityText":   strings.ToUpper(entry.Level.String()),
+		"SeverityNumber": otelSeverityNumber(entry.Level),
+		"Body":           entry.Message,
+	}
+	for key, value := range entry.Data {
+		switch key {
+		case "trace_id":
+			// TraceId must be 16 bytes in hex, other trace ids are kept as attributes
+			if traceID, ok := otelTraceID(value); ok {
+				data["TraceId"] = traceID
+			} else {
+				attributes[key] = fieldValue(value)
+			}
+		case "error", logrus.ErrorKey:
+			attributes["exception.message"] = fieldValue(value)
+		default:
+			attributes[key] = fieldValue(value)
+		}
+	}
+	// the span of the entry context takes precedence over trace ids of the fields
+	if spanContext := trace.SpanContextFromContext(entry.Context); spanContext.IsValid() {
+		data["TraceId"] = spanContext.TraceID().String()
+		data["SpanId"] = spanContext.SpanID().String()
+	}
+	if entry.HasCaller() {
+		attributes["code.function"] = entry.Caller.Function
+		attributes["code.filepath"] = entry.Caller.File
+		attributes["code.lineno"] = entry.Caller.Line
+	}
+	if len(attributes) > 0 {
+		data["Attributes"] = attributes
+	}
+	if f.ServiceName != "" {
+		data["Resource"] = map[string]interface{}{"service.name": f.ServiceName}
+	}
+
+	return writeJSON(data)
+}
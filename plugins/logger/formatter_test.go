@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestFormatters(t *testing.T) {
+	entry := &logrus.Entry{
+		Time:    time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		Level:   logrus.ErrorLevel,
+		Message: "Failed to process message",
+		Data: logrus.Fields{
+			"trace_id": "trace",
+			"error":    errors.New("boom"),
+			"consumer": "orders",
+		},
+	}
+
+	tests := []struct {
+		format   string
+		expected map[string]any
+	}{
+		{FormatECS, map[string]any{
+			"@timestamp":    "2022-01-01T00:00:00Z",
+			"log.level":     "error",
+			"message":       "Failed to process message",
+			"ecs.version":   ecsVersion,
+			"service.name":  "app",
+			"trace.id":      "trace",
+			"error.message": "boom",
+			"labels":        map[string]any{"consumer": "orders"},
+		}},
+		{FormatOtel, map[string]any{
+			"Timestamp":      "1640995200000000000",
+			"SeverityText":   "ERROR",
+			"SeverityNumber": float64(17),
+			"Body":           "Failed to process message",
+			"Attributes":     map[string]any{"consumer": "orders", "exception.message": "boom", "trace_id": "trace"},
+			"Resource":       map[string]any{"service.name": "app"},
+		}},
+	}
+	for _, tt := range tests {
+		formatter, err := NewFormatter(tt.format, "app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := formatter.Format(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result map[string]any
+		if err := json.Unmarshal(b, &result); err != nil {
+			t.Fatal(err)
+		}
+		expected, _ := json.Marshal(tt.expected)
+		actual, _ := json.Marshal(result)
+		if string(expected) != string(actual) {
+			t.Fatalf("%s entry is not formatted as %s but %s", tt.format, expected, actual)
+		}
+	}
+
+	entry.Data["trace_id"] = "0F6B2B3C-9A1D-4E5F-8A7B-6C5D4E3F2A1B"
+	b, _ := (&OtelFormatter{}).Format(entry)
+	var result map[string]any
+	json.Unmarshal(b, &result)
+	if result["TraceId"] != "0f6b2b3c9a1d4e5f8a7b6c5d4e3f2a1b" {
+		t.Fatalf("uuid trace id is not normalized, %v", result["TraceId"])
+	}
+
+	if _, err := NewFormatter("xml", ""); err == nil {
+		t.Fatal("unsupported format is accepted")
+	}
+}
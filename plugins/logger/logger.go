@@ -1,60 +1,307 @@
 package logger
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
-	joonix "github.com/joonix/log"
+	"github.com/shoplineapp/go-app/common"
 	"github.com/shoplineapp/go-app/plugins"
 	"github.com/shoplineapp/go-app/plugins/env"
 	"github.com/sirupsen/logrus"
+	"go.uber.org/fx"
 )
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewLogger)
+	plugins.Register(plugins.Plugin{
+		Name:         "logger",
+		DependsOn:    []string{"env"},
+		Constructors: []interface{}{ProvideLogger},
+	})
+	env.Declare(ProvideLogger,
+		env.Requirement{Name: "LOG_LEVEL", Description: "trace, debug or info, info in production and debug otherwise"},
+		env.Requirement{Name: "LOG_FORMAT", Description: "text, json, gcp, ecs or otel"},
+		env.Requirement{Name: "LOG_TO_CLOUDWATCH", Default: "false", Description: "Use the gcp format unless LOG_FORMAT is set"},
+		env.Requirement{Name: "APP_NAME", Description: "Service name of ecs and otel formatted entries"},
+		env.Requirement{Name: "LOG_OUTPUT", Default: "stdout", Description: "Comma separated stdout, stderr or file"},
+		env.Requirement{Name: "LOG_FILE_PATH", Description: "Path of the file output"},
+		env.Requirement{Name: "LOG_FILE_MAX_SIZE_MB", Default: strconv.Itoa(defaultFileMaxSizeMB), Description: "Size to rotate the log file at"},
+		env.Requirement{Name: "LOG_FILE_MAX_BACKUPS", Default: strconv.Itoa(defaultFileMaxBackups), Description: "Number of rotated log files to keep"},
+		env.Requirement{Name: "LOG_ASYNC", Default: "false", Description: "Write entries asynchronously"},
+		env.Requirement{Name: "LOG_ASYNC_BUFFER_SIZE", Default: strconv.Itoa(defaultAsyncBufferSize), Description: "Size of the asynchronous queue"},
+		env.Requirement{Name: "LOG_SAMPLING", Default: "off", Description: "Sampling policy of repeated entries, e.g. 10s:5"},
+		env.Requirement{Name: "LOG_SAMPLING_COMPONENTS", Description: "Sampling policies per component, e.g. sqs_worker=1m:3"},
+		env.Requirement{Name: "LOG_REDACT", Default: "true", Description: "Redact sensitive fields"},
+		env.Requirement{Name: "LOG_REDACT_SCAN", Default: "false", Description: "Scan entries for secrets such as emails"},
+	)
 }
 
 var logger *Logger
 
 type Logger struct {
 	logrus.Logger
+
+	redactHook  *RedactHook
+	sampler     *Sampler
+	sampling    sync.Once
+	stopFlush   chan struct{}
+	flushed     chan struct{}
+	asyncWriter *AsyncWriter
+	output      io.Writer
 }
 
 type Fields map[string]interface{}
 
+type LoggerParams struct {
+	fx.In
+
+	Lifecycle fx.Lifecycle `optional:"true"`
+	Env       *env.Env
+}
+
+// ProvideLogger creates the logger of the application, which is closed on stop of the lifecycle
+func ProvideLogger(params LoggerParams) *Logger {
+	plugin, unsubscribe := newLogger(params.Env)
+	if params.Lifecycle != nil {
+		params.Lifecycle.Append(fx.Hook{
+			OnStop: func(ctx context.Context) error {
+				unsubscribe()
+				return plugin.Close()
+			},
+		})
+	}
+	return plugin
+}
+
+// NewLogger creates a logger without lifecycle, Close must be called to flush pending entries
 func NewLogger(env *env.Env) *Logger {
-	l := logrus.Logger{
-		Out:          os.Stderr,
-		Formatter:    new(logrus.TextFormatter),
-		Hooks:        make(logrus.LevelHooks),
-		Level:        logrus.DebugLevel,
-		ExitFunc:     os.Exit,
-		ReportCaller: false,
+	plugin, _ := newLogger(env)
+	return plugin
+}
+
+func newLogger(env *env.Env) (*Logger, func()) {
+	plugin := &Logger{
+		Logger: logrus.Logger{
+			Out:          os.Stderr,
+			Formatter:    new(logrus.TextFormatter),
+			Hooks:        make(logrus.LevelHooks),
+			Level:        logrus.DebugLevel,
+			ExitFunc:     os.Exit,
+			ReportCaller: false,
+		},
 	}
+	l := &plugin.Logger
+
+	// Configuration errors are reported once the logger is usable, with the defaults in place
+	var configErrors []error
 
-	if env.GetEnv("ENVIRONMENT") == "production" || env.GetEnv("LOG_TO_CLOUDWATCH") == "true" {
-		l.SetFormatter(joonix.NewFormatter())
+	format := env.GetEnv("LOG_FORMAT")
+	if format == "" && (env.GetEnv("ENVIRONMENT") == "production" || env.GetEnv("LOG_TO_CLOUDWATCH") == "true") {
+		format = FormatGCP
+	}
+	if formatter, err := NewFormatter(format, env.GetEnv("APP_NAME")); err != nil {
+		configErrors = append(configErrors, err)
+	} else {
+		l.SetFormatter(formatter)
+	}
+	if format != "" && format != FormatText {
 		l.SetReportCaller(true)
 	}
 
-	switch level := env.GetEnv("LOG_LEVEL"); level {
+	// Sampling is off unless a window is configured, e.g. LOG_SAMPLING=10s:5
+	samplingPolicy, err := ParseSamplingPolicy(env.GetEnv("LOG_SAMPLING"))
+	if err != nil {
+		configErrors = append(configErrors, err)
+	}
+	sampler := NewSampler(samplingPolicy)
+	// Per component policies, e.g. LOG_SAMPLING_COMPONENTS=sqs_worker=1m:3,pulsar_consumer=off
+	for _, item := range strings.Split(env.GetEnv("LOG_SAMPLING_COMPONENTS"), ",") {
+		component, value, found := strings.Cut(strings.TrimSpace(item), "=")
+		if !found {
+			continue
+		}
+		policy, err := ParseSamplingPolicy(value)
+		if err != nil {
+			configErrors = append(configErrors, err)
+			continue
+		}
+		sampler.SetComponentPolicy(component, policy)
+	}
+
+	l.SetLevel(levelOf(env.GetEnv("LOG_LEVEL"), env.GetEnv("ENVIRONMENT")))
+
+	outputConfig := OutputConfig{
+		Outputs:        env.GetEnv("LOG_OUTPUT"),
+		FilePath:       env.GetEnv("LOG_FILE_PATH"),
+		FileMaxSizeMB:  env.GetEnvInt("LOG_FILE_MAX_SIZE_MB"),
+		FileMaxBackups: defaultFileMaxBackups,
+	}
+	if env.GetEnv("LOG_FILE_MAX_BACKUPS") != "" {
+		outputConfig.FileMaxBackups = env.GetEnvInt("LOG_FILE_MAX_BACKUPS")
+	}
+	output, err := NewOutput(outputConfig)
+	if err != nil {
+		configErrors = append(configErrors, err)
+		output = os.Stdout
+	}
+
+	var asyncWriter *AsyncWriter
+	if env.GetEnv("LOG_ASYNC") == "true" {
+		asyncWriter = NewAsyncWriter(output, env.GetEnvInt("LOG_ASYNC_BUFFER_SIZE"))
+		l.SetOutput(asyncWriter)
+	} else {
+		l.SetOutput(output)
+	}
+
+	// Redact sensitive fields of every entry unless LOG_REDACT is explicitly disabled
+	redactHook := NewRedactHook(common.DefaultRedactor)
+	// Scan string values, messages and errors for secrets such as emails or card numbers, with a redactor of
+	// the logger so other users of common.DefaultRedactor are not affected
+	if env.GetEnv("LOG_REDACT_SCAN") == "true" && common.DefaultRedactor.Scanner() == nil {
+		redactHook.SetRedactor(common.DefaultRedactor.WithScanner(common.NewScanner(common.DefaultDetectors()...)))
+	}
+	if env.GetEnv("LOG_REDACT") == "false" {
+		redactHook.SetRedactor(nil)
+	}
+	l.AddHook(redactHook)
+
+	plugin.redactHook = redactHook
+	plugin.sampler = sampler
+	if sampler.Enabled() {
+		plugin.startSampling()
+	}
+	plugin.asyncWriter = asyncWriter
+	plugin.output = output
+
+	for _, err := range configErrors {
+		plugin.WithField("error", err).Warn("Invalid logger configuration, fallback to default")
+	}
+
+	// Reconfigure without restart when the env is reloaded
+	unsubscribeLevel := env.Subscribe("LOG_LEVEL", func(value string) {
+		l.SetLevel(levelOf(value, env.GetEnv("ENVIRONMENT")))
+	})
+	unsubscribeSampling := env.Subscribe("LOG_SAMPLING", func(value string) {
+		policy, err := ParseSamplingPolicy(value)
+		if err != nil {
+			plugin.WithField("error", err).Warn("Invalid logger configuration, sampling is unchanged")
+			return
+		}
+		sampler.SetPolicy(policy)
+		if policy.Enabled() {
+			plugin.startSampling()
+		}
+	})
+
+	logger = plugin
+	return logger, func() {
+		unsubscribeLevel()
+		unsubscribeSampling()
+	}
+}
+
+func levelOf(value string, environment string) logrus.Level {
+	switch value {
 	case "trace":
-		l.SetLevel(logrus.TraceLevel)
+		return logrus.TraceLevel
 	case "debug":
-		l.SetLevel(logrus.DebugLevel)
+		return logrus.DebugLevel
 	case "info":
-		l.SetLevel(logrus.InfoLevel)
-	default:
-		if env.GetEnv("ENVIRONMENT") == "production" {
-			l.SetLevel(logrus.InfoLevel)
-		} else {
-			l.SetLevel(logrus.DebugLevel)
+		return logrus.InfoLevel
+	}
+	if environment == "production" {
+		return logrus.InfoLevel
+	}
+	return logrus.DebugLevel
+}
+
+// SetRedactor replaces the redactor applied to log entry fields, nil disables the redaction
+func (l *Logger) SetRedactor(redactor *common.Redactor) {
+	l.redactHook.SetRedactor(redactor)
+}
+
+// Component returns an entry tagged with the component name, which picks the sampling policy of the component
+func (l *Logger) Component(name string) *logrus.Entry {
+	return l.WithField(ComponentKey, name)
+}
+
+// SetSamplingPolicy overrides the sampling policy of entries of the component
+func (l *Logger) SetSamplingPolicy(component string, policy SamplingPolicy) {
+	l.sampler.SetComponentPolicy(component, policy)
+	if policy.Enabled() {
+		l.startSampling()
+	}
+}
+
+// startSampling installs the sampling formatter once sampling is configured, so unsampled entries skip the
+// sampler, and writes summaries of windows which are over at the interval until Close
+func (l *Logger) startSampling() {
+	l.sampling.Do(func() {
+		l.SetFormatter(&SamplingFormatter{Formatter: l.Formatter, Sampler: l.sampler})
+		l.stopFlush, l.flushed = make(chan struct{}), make(chan struct{})
+		go func() {
+			defer close(l.flushed)
+			ticker := time.NewTicker(samplerFlushInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-l.stopFlush:
+					return
+				case <-ticker.C:
+					l.writeSummaries(l.sampler.Expire())
+				}
+			}
+		}()
+	})
+}
+
+func (l *Logger) writeSummaries(summaries []SamplerSummary) {
+	for _, summary := range summaries {
+		level := summary.Level
+		// summaries are informative, never exit or panic for them
+		if level < logrus.ErrorLevel {
+			level = logrus.ErrorLevel
 		}
+		summary.Entry(&l.Logger, time.Now()).Log(level, summary.String())
 	}
-	l.SetOutput(os.Stdout)
+}
 
-	logger = &Logger{
-		Logger: l,
+// Dropped returns the number of entries dropped by the asynchronous output
+func (l *Logger) Dropped() uint64 {
+	if l.asyncWriter == nil {
+		return 0
 	}
+	return l.asyncWriter.Dropped()
+}
 
-	return logger
+// Close flushes pending entries and closes the sinks, the logger writes to stderr afterwards
+func (l *Logger) Close() error {
+	// stops sampling from then on, as Once is done either way
+	l.sampling.Do(func() {})
+	if l.stopFlush != nil {
+		select {
+		case <-l.stopFlush:
+		default:
+			close(l.stopFlush)
+		}
+		<-l.flushed
+	}
+	l.writeSummaries(l.sampler.Flush())
+
+	var err error
+	if l.asyncWriter != nil {
+		err = l.asyncWriter.Close()
+		if dropped := l.asyncWriter.Dropped(); dropped > 0 {
+			fmt.Fprintf(os.Stderr, "Logger dropped %d entries as the output queue was full\n", dropped)
+		}
+	} else {
+		err = closeOutput(l.output)
+	}
+	l.SetOutput(os.Stderr)
+	return err
 }
@@ -0,0 +1,269 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+	OutputFile   = "file"
+
+	defaultAsyncBufferSize = 4096
+	defaultFileMaxSizeMB   = 100
+	defaultFileMaxBackups  = 5
+)
+
+// AsyncWriter writes entries in a background goroutine through a bounded queue,
+// entries are dropped instead of blocking the caller when the queue is full
+type AsyncWriter struct {
+	out     io.Writer
+	queue   chan []byte
+	dropped uint64
+	closed  bool
+
+	mu   sync.RWMutex
+	done chan struct{}
+}
+
+func NewAsyncWriter(out io.Writer, bufferSize int) *AsyncWriter {
+	if bufferSize <= 0 {
+		bufferSize = defaultAsyncBufferSize
+	}
+	w := &AsyncWriter{
+		out:   out,
+		queue: make(chan []byte, bufferSize),
+		done:  make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+	for p := range w.queue {
+		if _, err := w.out.Write(p); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
+		}
+	}
+}
+
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		atomic.AddUint64(&w.dropped, 1)
+		return len(p), nil
+	}
+
+	// logrus reuses the buffer after Write returns
+	buf := make([]byte, len(p))
+	copy(buf, p)
+	select {
+	case w.queue <- buf:
+	default:
+		atomic.AddUint64(&w.dropped, 1)
+	}
+	return len(p), nil
+}
+
+// Dropped returns the number of entries dropped since the queue was full
+func (w *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+// Close flushes the queued entries and stops the background goroutine
+func (w *AsyncWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.queue)
+	w.mu.Unlock()
+
+	<-w.done
+	return closeOutput(w.out)
+}
+
+// RotatingFile is a file sink which rotates the file once it exceeds the max size,
+// rotated files are kept as <path>.1 (newest) to <path>.<maxBackups> (oldest)
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	file *os.File
+	size int64
+	mu   sync.Mutex
+}
+
+func NewRotatingFile(path string, maxSizeMB int, maxBackups int) (*RotatingFile, error) {
+	if maxSizeMB <= 0 {
+		maxSizeMB = defaultFileMaxSizeMB
+	}
+	if maxBackups < 0 {
+		maxBackups = defaultFileMaxBackups
+	}
+	f := &RotatingFile{
+		path:       path,
+		maxSize:    int64(maxSizeMB) * 1024 * 1024,
+		maxBackups: maxBackups,
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+func (f *RotatingFile) backupPath(n int) string {
+	return fmt.Sprintf("%s.%d", f.path, n)
+}
+
+// rotate reopens the original path when the file cannot be rotated, which keeps growing until the next rotation
+func (f *RotatingFile) rotate() error {
+	err := f.file.Close()
+	f.file = nil
+	if err == nil {
+		if f.maxBackups == 0 {
+			os.Remove(f.path)
+		} else {
+			os.Remove(f.backupPath(f.maxBackups))
+			for n := f.maxBackups - 1; n > 0; n-- {
+				os.Rename(f.backupPath(n), f.backupPath(n+1))
+			}
+			err = os.Rename(f.path, f.backupPath(1))
+		}
+	}
+	return errors.Join(err, f.open())
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		// reopening failed on the last rotation
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	if f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil && f.file == nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Close()
+}
+
+// multiWriteCloser closes every underlying sink on Close
+type multiWriteCloser struct {
+	io.Writer
+	writers []io.Writer
+}
+
+func (m multiWriteCloser) Close() error {
+	var err error
+	for _, w := range m.writers {
+		if cErr := closeOutput(w); cErr != nil {
+			err = cErr
+		}
+	}
+	return err
+}
+
+// closeOutput closes the sink unless it is a standard stream
+func closeOutput(w io.Writer) error {
+	if w == os.Stdout || w == os.Stderr {
+		return nil
+	}
+	if closer, ok := w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// OutputConfig describes the sinks of log entries
+type OutputConfig struct {
+	// Comma separated sinks, possible values: stdout, stderr, file
+	Outputs        string
+	FilePath       string
+	FileMaxSizeMB  int
+	FileMaxBackups int
+}
+
+// NewOutput returns a writer to all sinks of the config
+func NewOutput(config OutputConfig) (_ io.Writer, err error) {
+	var writers []io.Writer
+	defer func() {
+		// sinks opened ahead of an invalid one are closed
+		if err != nil {
+			multiWriteCloser{writers: writers}.Close()
+		}
+	}()
+	for _, output := range strings.Split(config.Outputs, ",") {
+		switch strings.TrimSpace(strings.ToLower(output)) {
+		case OutputStdout, "":
+			writers = append(writers, os.Stdout)
+		case OutputStderr:
+			writers = append(writers, os.Stderr)
+		case OutputFile:
+			if config.FilePath == "" {
+				return nil, fmt.Errorf("LOG_FILE_PATH is required for file output")
+			}
+			file, err := NewRotatingFile(config.FilePath, config.FileMaxSizeMB, config.FileMaxBackups)
+			if err != nil {
+				return nil, err
+			}
+			writers = append(writers, file)
+		default:
+			return nil, fmt.Errorf("unsupported log output %q", output)
+		}
+	}
+
+	if len(writers) == 1 {
+		return writers[0], nil
+	}
+	return multiWriteCloser{Writer: io.MultiWriter(writers...), writers: writers}, nil
+}
@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+type blockingWriter struct {
+	bytes.Buffer
+	release chan struct{}
+	mu      sync.Mutex
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.Buffer.Write(p)
+}
+
+func TestAsyncWriter(t *testing.T) {
+	t.Run("flushes on close", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		w := NewAsyncWriter(out, 10)
+		w.Write([]byte("a\n"))
+		w.Write([]byte("b\n"))
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != "a\nb\n" {
+			t.Fatalf("entries are not flushed but %q", out.String())
+		}
+	})
+	t.Run("drops when queue is full", func(t *testing.T) {
+		out := &blockingWriter{release: make(chan struct{})}
+		w := NewAsyncWriter(out, 1)
+		for i := 0; i < 5; i++ {
+			w.Write([]byte("a\n"))
+		}
+		close(out.release)
+		w.Close()
+
+		// one entry is held by the writer goroutine and one in the queue at most
+		if dropped := w.Dropped(); dropped < 3 {
+			t.Fatalf("expected at least 3 dropped entries but %d", dropped)
+		}
+		if written := uint64(len(out.String()) / 2); written+w.Dropped() != 5 {
+			t.Fatalf("written %d and dropped %d entries does not add up to 5", written, w.Dropped())
+		}
+	})
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	f, err := NewRotatingFile(path, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ~1MB per write to rotate on every write
+	entry := bytes.Repeat([]byte("a"), 1024*1024-1)
+	for i := 0; i < 4; i++ {
+		if _, err := f.Write(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.Close()
+
+	for _, p := range []string{path, path + ".1", path + ".2"} {
+		if _, err := os.Stat(p); err != nil {
+			t.Fatalf("%s is not found: %v", p, err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatal("backups are not limited to 2")
+	}
+}
+
+func TestRotatingFileRenameFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	// a non-empty directory in place of the backup fails the rename
+	if err := os.MkdirAll(filepath.Join(path+".1", "blocked"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewRotatingFile(path, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	entry := bytes.Repeat([]byte("a"), 1024*1024-1)
+	for i := 0; i < 3; i++ {
+		if _, err := f.Write(entry); err != nil {
+			t.Fatalf("write %d fails after the rotation failed, %v", i, err)
+		}
+	}
+	if info, _ := os.Stat(path); info.Size() != 3*int64(len(entry)) {
+		t.Fatal("entries are not written to the original path")
+	}
+}
+
+func TestNewOutputClosesOnError(t *testing.T) {
+	if _, err := os.Stat("/proc/self/fd"); err != nil {
+		t.Skip("open files are not listed")
+	}
+	path := filepath.Join(t.TempDir(), "app.log")
+	if _, err := NewOutput(OutputConfig{Outputs: "file,unknown", FilePath: path}); err == nil {
+		t.Fatal("unsupported output is accepted")
+	}
+
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fd := range fds {
+		if target, _ := os.Readlink(filepath.Join("/proc/self/fd", fd.Name())); target == path {
+			t.Fatal("file opened ahead of the invalid output is not closed")
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/shoplineapp/go-app/common"
+	"github.com/sirupsen/logrus"
+)
+
+// unredacted marks a field value which should be logged as-is
+type unredacted struct {
+	value interface{}
+}
+
+// Unredacted opts a field value out of the redaction hook, e.g. a payload which is already redacted
+//
+//	logger.WithField("req", logger.Unredacted(redactor.Redact(req))).Info("Request Executed")
+func Unredacted(value interface{}) interface{} {
+	return unredacted{value: value}
+}
+
+// RedactHook runs the redactor over all fields of an entry before it is formatted
+type RedactHook struct {
+	redactor *common.Redactor
+	mu       sync.RWMutex
+}
+
+func NewRedactHook(redactor *common.Redactor) *RedactHook {
+	return &RedactHook{redactor: redactor}
+}
+
+func (h *RedactHook) SetRedactor(redactor *common.Redactor) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.redactor = redactor
+}
+
+func (h *RedactHook) Redactor() *common.Redactor {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.redactor
+}
+
+func (h *RedactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *RedactHook) Fire(entry *logrus.Entry) error {
+	redactor := h.Redactor()
+	if redactor != nil {
+		// no-op unless the redactor has a scanner
+		entry.Message = redactor.ScanString(entry.Message)
+	}
+	if len(entry.Data) == 0 {
+		return nil
+	}
+
+	data := make(logrus.Fields, len(entry.Data))
+	redactable := make(map[string]interface{}, len(entry.Data))
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case unredacted:
+			data[key] = v.value
+		case error:
+			// keep errors so formatters can still render them with Error(), with secrets in the message replaced
+			if redactor == nil {
+				data[key] = v
+				continue
+			}
+			data[key] = redactor.RedactError(v)
+		default:
+			if redactor == nil {
+				data[key] = v
+				continue
+			}
+			redactable[key] = v
+		}
+	}
+
+	if len(redactable) > 0 {
+		// Redact returns either the original map or a redacted copy, both keyed by field name
+		redacted, ok := redactor.Redact(redactable).(map[string]interface{})
+		if !ok {
+			// never log the fields unredacted, nor drop them silently
+			for key := range redactable {
+				data[key] = common.FullRedact(reflect.Value{})
+			}
+		}
+		for key, value := range redacted {
+			data[key] = value
+		}
+	}
+
+	entry.Data = data
+	return nil
+}
@@ -0,0 +1,146 @@
+package logger
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/shoplineapp/go-app/common"
+	"github.com/shoplineapp/go-app/plugins/env"
+	"github.com/sirupsen/logrus"
+)
+
+type testMessage struct {
+	Email   string `json:"email"`
+	Address string `json:"address"`
+	Note    string `json:"note"`
+}
+
+func newTestEntry(fields logrus.Fields) *logrus.Entry {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	return logrus.NewEntry(l).WithFields(fields)
+}
+
+func TestRedactHookFire(t *testing.T) {
+	t.Run("redacts fields", func(t *testing.T) {
+		entry := newTestEntry(logrus.Fields{
+			"password": "secret",
+			"message":  testMessage{Email: "email@email.com", Address: "somewhere", Note: "hello"},
+			"count":    1,
+		})
+		if err := NewRedactHook(common.DefaultRedactor).Fire(entry); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := logrus.Fields{
+			"password": "<REDACTED>",
+			"message": map[string]any{
+				"email":   "emai*****",
+				"address": "<REDACTED>",
+				"note":    "hello",
+			},
+			"count": 1,
+		}
+		if !reflect.DeepEqual(entry.Data, expected) {
+			t.Fatalf("entry is not redacted as %v but %v", expected, entry.Data)
+		}
+	})
+	t.Run("keeps unredacted and error fields", func(t *testing.T) {
+		err := errors.New("password is wrong")
+		entry := newTestEntry(logrus.Fields{
+			"password": Unredacted("secret"),
+			"error":    err,
+		})
+		if err := NewRedactHook(common.DefaultRedactor).Fire(entry); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := logrus.Fields{"password": "secret", "error": err}
+		if !reflect.DeepEqual(entry.Data, expected) {
+			t.Fatalf("entry is not kept as %v but %v", expected, entry.Data)
+		}
+	})
+	t.Run("disabled without redactor", func(t *testing.T) {
+		entry := newTestEntry(logrus.Fields{"password": "secret", "email": Unredacted("email@email.com")})
+		if err := NewRedactHook(nil).Fire(entry); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := logrus.Fields{"password": "secret", "email": "email@email.com"}
+		if !reflect.DeepEqual(entry.Data, expected) {
+			t.Fatalf("entry is not kept as %v but %v", expected, entry.Data)
+		}
+	})
+	t.Run("scans message, strings and errors", func(t *testing.T) {
+		redactor := &common.Redactor{}
+		redactor.SetScanner(common.NewScanner(common.DefaultDetectors()...))
+		cause := errors.New("user email@email.com not found")
+		entry := newTestEntry(logrus.Fields{
+			"note":  "call me at +852 9123 4567",
+			"error": cause,
+		})
+		entry.Message = "paid by 4111 1111 1111 1111"
+		if err := NewRedactHook(redactor).Fire(entry); err != nil {
+			t.Fatal(err)
+		}
+
+		if entry.Message != "paid by <REDACTED:card>" || entry.Data["note"] != "call me at <REDACTED:phone>" {
+			t.Fatalf("entry is not scanned but %s %v", entry.Message, entry.Data)
+		}
+		err, _ := entry.Data["error"].(error)
+		if err == nil || err.Error() != "user <REDACTED:email> not found" || !errors.Is(err, cause) {
+			t.Fatalf("error is not scanned but %v", entry.Data["error"])
+		}
+	})
+}
+
+func benchmarkLogger(hook logrus.Hook) *logrus.Logger {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	l.SetFormatter(&logrus.JSONFormatter{})
+	if hook != nil {
+		l.AddHook(hook)
+	}
+	return l
+}
+
+func benchmarkFields() logrus.Fields {
+	return logrus.Fields{
+		"trace_id": "5f0e4c1e-6a55-4c43-9c55-2f9b8e2f0c11",
+		"consumer": map[string]string{"consumer_label": "orders", "consumer_topic": "persistent://public/default/orders"},
+		"message":  testMessage{Email: "email@email.com", Address: "somewhere", Note: "hello"},
+	}
+}
+
+func BenchmarkLoggerWithoutRedactHook(b *testing.B) {
+	l := benchmarkLogger(nil)
+	fields := benchmarkFields()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.WithFields(fields).Info("Received message")
+	}
+}
+
+func BenchmarkLoggerWithRedactHook(b *testing.B) {
+	l := benchmarkLogger(NewRedactHook(common.DefaultRedactor))
+	fields := benchmarkFields()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.WithFields(fields).Info("Received message")
+	}
+}
+
+func TestLoggerRedactScan(t *testing.T) {
+	t.Setenv("PROJECT_ROOT", t.TempDir())
+	t.Setenv("LOG_REDACT_SCAN", "true")
+	l := NewLogger(env.NewEnv())
+	defer l.Close()
+	if common.DefaultRedactor.Scanner() != nil {
+		t.Fatal("scanner is set to the default redactor")
+	}
+	if l.redactHook.Redactor().ScanString("contact email@email.com") == "contact email@email.com" {
+		t.Fatal("entries of the logger are not scanned")
+	}
+}
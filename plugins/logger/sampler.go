@@ -0,0 +1,284 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ComponentKey is the field name of the component producing the entry, used to pick a sampling policy
+	ComponentKey = "component"
+
+	defaultSamplingFirst = 10
+
+	// Expired keys are pruned once there are more keys than the limit
+	samplerPruneThreshold = 10000
+
+	// Summaries of windows which are over are written at the interval, see Logger.startSampling
+	samplerFlushInterval = time.Second
+)
+
+// SamplingPolicy emits the first N entries of identical message and level within the window,
+// the rest are suppressed and summarized once the window is over
+type SamplingPolicy struct {
+	Window time.Duration
+	First  int
+}
+
+func (p SamplingPolicy) Enabled() bool {
+	return p.Window > 0
+}
+
+// ParseSamplingPolicy parses a policy in format of <window>[:<first>], e.g. 10s:5, or off
+func ParseSamplingPolicy(value string) (SamplingPolicy, error) {
+	policy := SamplingPolicy{First: defaultSamplingFirst}
+	value = strings.TrimSpace(value)
+	if value == "" || value == "off" {
+		return SamplingPolicy{}, nil
+	}
+
+	parts := strings.SplitN(value, ":", 2)
+	window, err := time.ParseDuration(parts[0])
+	if err != nil {
+		return policy, fmt.Errorf("invalid sampling window %q: %w", parts[0], err)
+	}
+	policy.Window = window
+	if len(parts) == 2 {
+		first, err := strconv.Atoi(parts[1])
+		if err != nil || first < 0 {
+			return policy, fmt.Errorf("invalid sampling first %q", parts[1])
+		}
+		policy.First = first
+	}
+	return policy, nil
+}
+
+type samplerKey struct {
+	component string
+	level     logrus.Level
+	message   string
+}
+
+type samplerCounter struct {
+	start      time.Time
+	count      int
+	suppressed int
+	policy     SamplingPolicy
+}
+
+func (c *samplerCounter) summary(key samplerKey) *SamplerSummary {
+	return &SamplerSummary{
+		Component:  key.component,
+		Level:      key.level,
+		Message:    key.message,
+		Suppressed: c.suppressed,
+		Window:     c.policy.Window,
+	}
+}
+
+// Sampler deduplicates identical entries, see SamplingPolicy
+type Sampler struct {
+	policy     SamplingPolicy
+	components map[string]SamplingPolicy
+	counters   map[samplerKey]*samplerCounter
+	now        func() time.Time
+
+	mu sync.Mutex
+}
+
+func NewSampler(policy SamplingPolicy) *Sampler {
+	return &Sampler{
+		policy:     policy,
+		components: map[string]SamplingPolicy{},
+		counters:   map[samplerKey]*samplerCounter{},
+		now:        time.Now,
+	}
+}
+
+// SetPolicy replaces the default policy
+func (s *Sampler) SetPolicy(policy SamplingPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policy = policy
+}
+
+// SetComponentPolicy overrides the default policy for entries with the component field
+func (s *Sampler) SetComponentPolicy(component string, policy SamplingPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.components[component] = policy
+}
+
+// Enabled returns whether the default policy or any component policy samples entries
+func (s *Sampler) Enabled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.policy.Enabled() {
+		return true
+	}
+	for _, policy := range s.components {
+		if policy.Enabled() {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Sampler) policyOf(component string) SamplingPolicy {
+	if policy, ok := s.components[component]; ok {
+		return policy
+	}
+	return s.policy
+}
+
+// Sample returns whether the entry should be emitted, and the summary of entries suppressed
+// in the previous window of the same key which should be written ahead of the entry
+func (s *Sampler) Sample(entry *logrus.Entry) (bool, *SamplerSummary) {
+	if entry.Context != nil && entry.Context.Value(summaryKey{}) != nil {
+		return true, nil
+	}
+	component, _ := entry.Data[ComponentKey].(string)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	policy := s.policyOf(component)
+	if !policy.Enabled() {
+		return true, nil
+	}
+
+	now := s.now()
+	key := samplerKey{component: component, level: entry.Level, message: entry.Message}
+	counter, ok := s.counters[key]
+	if !ok || now.Sub(counter.start) >= policy.Window {
+		var summary *SamplerSummary
+		if ok && counter.suppressed > 0 {
+			summary = counter.summary(key)
+		} else if !ok && len(s.counters) >= samplerPruneThreshold {
+			s.prune(now)
+		}
+		counter := &samplerCounter{start: now, count: 1, policy: policy}
+		if policy.First == 0 {
+			// the first entry is suppressed too
+			counter.suppressed = 1
+		}
+		s.counters[key] = counter
+		return policy.First > 0, summary
+	}
+
+	counter.count++
+	if counter.count > policy.First {
+		counter.suppressed++
+		return false, nil
+	}
+	return true, nil
+}
+
+func (s *Sampler) prune(now time.Time) {
+	for key, counter := range s.counters {
+		if counter.suppressed == 0 && now.Sub(counter.start) >= counter.policy.Window {
+			delete(s.counters, key)
+		}
+	}
+}
+
+// Expire returns the summaries of windows which are over, and removes their counters
+func (s *Sampler) Expire() []SamplerSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var summaries []SamplerSummary
+	for key, counter := range s.counters {
+		if now.Sub(counter.start) < counter.policy.Window {
+			continue
+		}
+		if counter.suppressed > 0 {
+			summaries = append(summaries, *counter.summary(key))
+		}
+		delete(s.counters, key)
+	}
+	return summaries
+}
+
+// Flush returns the summaries of all suppressed entries and resets the counters
+func (s *Sampler) Flush() []SamplerSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var summaries []SamplerSummary
+	for key, counter := range s.counters {
+		if counter.suppressed > 0 {
+			summaries = append(summaries, *counter.summary(key))
+		}
+	}
+	s.counters = map[samplerKey]*samplerCounter{}
+	return summaries
+}
+
+type SamplerSummary struct {
+	Component  string
+	Level      logrus.Level
+	Message    string
+	Suppressed int
+	Window     time.Duration
+}
+
+func (s SamplerSummary) String() string {
+	return fmt.Sprintf("%s (suppressed %d times in %s)", s.Message, s.Suppressed, s.Window)
+}
+
+// summaryKey marks summary entries in the entry context, which are never sampled
+type summaryKey struct{}
+
+// Entry builds the summary entry of the logger
+func (s SamplerSummary) Entry(l *logrus.Logger, t time.Time) *logrus.Entry {
+	fields := logrus.Fields{"suppressed": s.Suppressed}
+	if s.Component != "" {
+		fields[ComponentKey] = s.Component
+	}
+	entry := logrus.NewEntry(l).WithFields(fields).WithContext(context.WithValue(context.Background(), summaryKey{}, true))
+	entry.Time = t
+	entry.Level = s.Level
+	entry.Message = s.String()
+	return entry
+}
+
+// SamplingFormatter drops entries suppressed by the sampler, formatted summaries are
+// written ahead of the first entry of the next window, or by the logger once the window is over
+type SamplingFormatter struct {
+	logrus.Formatter
+	Sampler *Sampler
+}
+
+func (f *SamplingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	emit, s := f.Sampler.Sample(entry)
+
+	var summary []byte
+	if s != nil {
+		formatted, err := f.Formatter.Format(s.Entry(entry.Logger, entry.Time))
+		if err != nil {
+			return nil, err
+		}
+		// formatters may return a buffer reused by the next Format call
+		summary = append([]byte{}, formatted...)
+	}
+	if !emit {
+		return summary, nil
+	}
+
+	serialized, err := f.Formatter.Format(entry)
+	if err != nil {
+		return nil, err
+	}
+	if summary == nil {
+		return serialized, nil
+	}
+	return append(summary, serialized...), nil
+}
@@ -0,0 +1,149 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shoplineapp/go-app/plugins/env"
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseSamplingPolicy(t *testing.T) {
+	tests := []struct {
+		value  string
+		policy SamplingPolicy
+		err    bool
+	}{
+		{"", SamplingPolicy{}, false},
+		{"off", SamplingPolicy{}, false},
+		{"10s", SamplingPolicy{Window: 10 * time.Second, First: defaultSamplingFirst}, false},
+		{"1m:3", SamplingPolicy{Window: time.Minute, First: 3}, false},
+		{"1m:-1", SamplingPolicy{}, true},
+		{"abc", SamplingPolicy{}, true},
+	}
+	for _, tt := range tests {
+		policy, err := ParseSamplingPolicy(tt.value)
+		if (err != nil) != tt.err {
+			t.Fatalf("unexpected error of %q: %v", tt.value, err)
+		}
+		if err == nil && policy != tt.policy {
+			t.Fatalf("%q is not parsed as %+v but %+v", tt.value, tt.policy, policy)
+		}
+	}
+}
+
+func newSampledLogger(policy SamplingPolicy) (*logrus.Logger, *Sampler, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	sampler := NewSampler(policy)
+	l := logrus.New()
+	l.SetOutput(out)
+	l.SetFormatter(&SamplingFormatter{
+		Formatter: &logrus.TextFormatter{DisableTimestamp: true},
+		Sampler:   sampler,
+	})
+	return l, sampler, out
+}
+
+func TestSamplingFormatter(t *testing.T) {
+	t.Run("suppresses repeated entries and summarizes in next window", func(t *testing.T) {
+		l, sampler, out := newSampledLogger(SamplingPolicy{Window: time.Minute, First: 2})
+		now := time.Now()
+		sampler.now = func() time.Time { return now }
+
+		for i := 0; i < 5; i++ {
+			l.Error("Failed to fetch sqs message")
+		}
+		l.Info("Failed to fetch sqs message")
+		if lines := strings.Count(out.String(), "\n"); lines != 3 {
+			t.Fatalf("expected 3 lines but %d: %s", lines, out.String())
+		}
+
+		out.Reset()
+		now = now.Add(time.Minute)
+		l.Error("Failed to fetch sqs message")
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != 2 || !strings.Contains(lines[0], "suppressed 3 times in 1m0s") || !strings.Contains(lines[0], "suppressed=3") {
+			t.Fatalf("summary is not written ahead of the entry: %s", out.String())
+		}
+	})
+	t.Run("applies component policy", func(t *testing.T) {
+		l, sampler, out := newSampledLogger(SamplingPolicy{})
+		sampler.SetComponentPolicy("sqs_worker", SamplingPolicy{Window: time.Minute, First: 1})
+
+		for i := 0; i < 3; i++ {
+			l.WithField(ComponentKey, "sqs_worker").Error("Failed to fetch sqs message")
+			l.WithField(ComponentKey, "pulsar_consumer").Error("Failed to fetch sqs message")
+		}
+		if lines := strings.Count(out.String(), "\n"); lines != 4 {
+			t.Fatalf("expected 4 lines but %d: %s", lines, out.String())
+		}
+
+		summaries := sampler.Flush()
+		if len(summaries) != 1 || summaries[0].Component != "sqs_worker" || summaries[0].Suppressed != 2 {
+			t.Fatalf("unexpected summaries %+v", summaries)
+		}
+	})
+	t.Run("counts every entry suppressed without first ones", func(t *testing.T) {
+		l, sampler, out := newSampledLogger(SamplingPolicy{Window: time.Minute})
+		now := time.Now()
+		sampler.now = func() time.Time { return now }
+		l.Error("Failed to fetch sqs message")
+		l.Warn("Failed to fetch sqs message")
+		l.Warn("Failed to fetch sqs message")
+		if out.Len() > 0 {
+			t.Fatalf("entries are written without first ones: %s", out.String())
+		}
+
+		now = now.Add(time.Minute)
+		summaries := sampler.Expire()
+		if len(summaries) != 2 {
+			t.Fatalf("unexpected summaries %+v", summaries)
+		}
+		for _, summary := range summaries {
+			if expected := map[logrus.Level]int{logrus.ErrorLevel: 1, logrus.WarnLevel: 2}[summary.Level]; summary.Suppressed != expected {
+				t.Fatalf("summary of %s is not counted as %d but %d", summary.Level, expected, summary.Suppressed)
+			}
+		}
+	})
+	t.Run("summarizes windows which are over", func(t *testing.T) {
+		l, sampler, out := newSampledLogger(SamplingPolicy{Window: time.Minute, First: 1})
+		now := time.Now()
+		sampler.now = func() time.Time { return now }
+		for i := 0; i < 3; i++ {
+			l.Error("Failed to fetch sqs message")
+		}
+		if summaries := sampler.Expire(); len(summaries) != 0 {
+			t.Fatalf("summaries of the current window are expired, %+v", summaries)
+		}
+		now = now.Add(time.Minute)
+		summaries := sampler.Expire()
+		if len(summaries) != 1 || summaries[0].Suppressed != 2 {
+			t.Fatalf("unexpected summaries %+v", summaries)
+		}
+
+		// summaries are never sampled
+		out.Reset()
+		for i := 0; i < 2; i++ {
+			summaries[0].Entry(l, now).Log(logrus.ErrorLevel, summaries[0].String())
+		}
+		if lines := strings.Count(out.String(), "suppressed 2 times"); lines != 2 {
+			t.Fatalf("summaries are sampled: %s", out.String())
+		}
+	})
+}
+
+func TestLoggerSampling(t *testing.T) {
+	t.Setenv("PROJECT_ROOT", t.TempDir())
+	t.Setenv("LOG_SAMPLING", "")
+	l := NewLogger(env.NewEnv())
+	defer l.Close()
+	if _, ok := l.Formatter.(*SamplingFormatter); ok {
+		t.Fatal("sampling formatter is installed without sampling policies")
+	}
+	l.SetSamplingPolicy("sqs_worker", SamplingPolicy{Window: time.Minute, First: 1})
+	if _, ok := l.Formatter.(*SamplingFormatter); !ok {
+		t.Fatal("sampling formatter is not installed with a component policy")
+	}
+}
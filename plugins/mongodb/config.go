@@ -0,0 +1,22 @@
+//go:build mongodb
+// +build mongodb
+
+package mongodb
+
+import "github.com/shoplineapp/go-app/plugins/env"
+
+// Config of the default mongo session
+type Config struct {
+	Protocol string `env:"ATLAS_MONGOID_SESSIONS_DEFAULT_PROTOCOL" default:"mongodb"`
+	Username string `env:"ATLAS_MONGOID_SESSIONS_DEFAULT_USERNAME"`
+	Password string `env:"ATLAS_MONGOID_SESSIONS_DEFAULT_PASSWORD"`
+	Hosts    string `env:"ATLAS_MONGOID_SESSIONS_DEFAULT_SRV_URI" required:"true"`
+	Database string `env:"ATLAS_MONGOID_SESSIONS_DEFAULT_DATABASE" required:"true"`
+	Params   string `env:"ATLAS_MONGOID_SESSIONS_DEFAULT_PARAMS"`
+}
+
+func LoadConfig(e *env.Env) (Config, error) {
+	var config Config
+	err := e.Bind(&config)
+	return config, err
+}
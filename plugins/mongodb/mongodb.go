@@ -18,7 +18,19 @@ import (
 )
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewMongoStore)
+	plugins.Register(plugins.Plugin{
+		Name:         "mongodb",
+		Tags:         []string{"database"},
+		DependsOn:    []string{"env", "logger"},
+		Constructors: []interface{}{NewMongoStore},
+	})
+	// connection arguments are given to Connect, so none of them is required
+	env.Declare(NewMongoStore,
+		env.Requirement{Name: "ATLAS_MONGOID_SESSIONS_DEFAULT_SRV_URI", Description: "Hosts of the default mongo session"},
+		env.Requirement{Name: "ATLAS_MONGOID_SESSIONS_DEFAULT_DATABASE", Description: "Database of the default mongo session"},
+		env.Requirement{Name: "ATLAS_MONGOID_SESSIONS_DEFAULT_USERNAME", Description: "Username of the default mongo session"},
+		env.Requirement{Name: "ATLAS_MONGOID_SESSIONS_DEFAULT_PASSWORD", Secret: true, Description: "Password of the default mongo session"},
+	)
 }
 
 var MONGODB_CONNECTION_TIMEOUT = 10 * time.Second
@@ -59,6 +71,11 @@ func (s MongoStore) Collection(name string) *mgm.Collection {
 	return mgm.NewCollection(s.db, name)
 }
 
+// Client returns the connected client, e.g. to start sessions of transactions
+func (s MongoStore) Client() *mongo.Client {
+	return s.client
+}
+
 func (s *MongoStore) Connect(protocol string, username string, password string, hosts string, databaseName string, params string, opts ...*options.ClientOptions) {
 	connectURL := generateConnectURL(protocol, username, password, hosts, databaseName, params)
 
@@ -86,6 +103,16 @@ func (s *MongoStore) Connect(protocol string, username string, password string,
 	s.db = client.Database(databaseName)
 }
 
+// ConnectWithConfig connects mongo with the config, e.g. from LoadConfig
+func (s *MongoStore) ConnectWithConfig(config Config, opts ...*options.ClientOptions) {
+	s.Connect(config.Protocol, config.Username, config.Password, config.Hosts, config.Database, config.Params, opts...)
+}
+
+// Config returns the config of the default session bound from env
+func (s MongoStore) Config() (Config, error) {
+	return LoadConfig(s.env)
+}
+
 func NewMongoStore(env *env.Env, logger *logger.Logger) *MongoStore {
 
 	store := &MongoStore{
@@ -10,17 +10,32 @@ import (
 )
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewNewrelicAgent)
+	plugins.Register(plugins.Plugin{
+		Name:         "newrelic",
+		Tags:         []string{"observability"},
+		Constructors: []interface{}{NewNewrelicAgent},
+		Noop:         []interface{}{NewNoopNewrelicAgent},
+	})
 }
 
 var app *newrelic.Application
 
-type NewrelicAgent struct{}
+type NewrelicAgent struct {
+	disabled bool
+}
 
+// App returns the configured application, which is nil if the plugin is disabled
 func (a NewrelicAgent) App() *newrelic.Application {
+	if a.disabled {
+		return nil
+	}
 	return app
 }
 
+func (a NewrelicAgent) Enabled() bool {
+	return !a.disabled
+}
+
 func Configure(configs ...newrelic.ConfigOption) {
 	a, err := newrelic.NewApplication(configs...)
 	if err != nil {
@@ -33,3 +48,8 @@ func Configure(configs ...newrelic.ConfigOption) {
 func NewNewrelicAgent() *NewrelicAgent {
 	return &NewrelicAgent{}
 }
+
+// NewNoopNewrelicAgent is provided when the plugin is disabled, transactions of nil application are noop
+func NewNoopNewrelicAgent() *NewrelicAgent {
+	return &NewrelicAgent{disabled: true}
+}
@@ -18,10 +18,21 @@ import (
 )
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewOtelAgent)
+	plugins.Register(plugins.Plugin{
+		Name:         "otel",
+		Tags:         []string{"observability"},
+		Constructors: []interface{}{NewOtelAgent},
+		Noop:         []interface{}{NewNoopOtelAgent},
+	})
 }
 
-type OtelAgent struct{}
+type OtelAgent struct {
+	disabled bool
+}
+
+func (a *OtelAgent) Enabled() bool {
+	return !a.disabled
+}
 
 type OtelConfig struct {
 	AppName string
@@ -60,3 +71,9 @@ func GetTracer() trace.Tracer {
 func NewOtelAgent() *OtelAgent {
 	return &OtelAgent{}
 }
+
+// NewNoopOtelAgent is provided when the plugin is disabled, spans of the default global tracer provider are noop
+// unless Configure is called
+func NewNoopOtelAgent() *OtelAgent {
+	return &OtelAgent{disabled: true}
+}
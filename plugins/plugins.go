@@ -1,3 +1,170 @@
 package plugins
 
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
 var Registry []interface{} = []interface{}{}
+
+// Plugin is the metadata of a registered plugin
+type Plugin struct {
+	Name string
+	// Tags select plugins by group, e.g. observability
+	Tags []string
+	// DependsOn are the names of plugins the constructors depend on
+	DependsOn    []string
+	Constructors []interface{}
+	// Noop constructors are provided instead when the plugin is disabled, so dependents still resolve
+	Noop []interface{}
+}
+
+func (p Plugin) matches(names []string) bool {
+	for _, name := range names {
+		if name == p.Name {
+			return true
+		}
+		for _, tag := range p.Tags {
+			if name == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var (
+	registered   []Plugin
+	registeredMu sync.RWMutex
+)
+
+// Register adds the plugin constructors to the Registry with metadata for selection
+//
+//	func init() {
+//		plugins.Register(plugins.Plugin{
+//			Name:         "sentry",
+//			Tags:         []string{"observability"},
+//			DependsOn:    []string{"env", "logger"},
+//			Constructors: []interface{}{NewSentryAgent},
+//			Noop:         []interface{}{NewNoopSentryAgent},
+//		})
+//	}
+func Register(plugin Plugin) {
+	registeredMu.Lock()
+	defer registeredMu.Unlock()
+	registered = append(registered, plugin)
+	Registry = append(Registry, plugin.Constructors...)
+}
+
+// Registered returns the registered plugins in order of registration
+func Registered() []Plugin {
+	registeredMu.RLock()
+	defer registeredMu.RUnlock()
+	return append([]Plugin{}, registered...)
+}
+
+// Lookup returns the registered plugin of the name
+func Lookup(name string) (Plugin, bool) {
+	for _, plugin := range Registered() {
+		if plugin.Name == name {
+			return plugin, true
+		}
+	}
+	return Plugin{}, false
+}
+
+// Exists returns whether a registered plugin has the name or tag
+func Exists(name string) bool {
+	for _, plugin := range Registered() {
+		if plugin.matches([]string{name}) {
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns the constructors of the Registry with plugins selected by names or tags. All plugins are
+// enabled if enable is empty, otherwise only the matched ones and their dependencies. Disabled plugins are
+// substituted by their noop constructors, and it is an error if an enabled plugin depends on a disabled one
+// without noop. Constructors appended to the Registry without metadata are always selected.
+func Select(enable []string, disable []string) ([]interface{}, error) {
+	all := Registered()
+	byName := map[string]Plugin{}
+	for _, plugin := range all {
+		byName[plugin.Name] = plugin
+	}
+
+	enabled := map[string]bool{}
+	var include func(plugin Plugin)
+	include = func(plugin Plugin) {
+		if enabled[plugin.Name] {
+			return
+		}
+		enabled[plugin.Name] = true
+		for _, dependency := range plugin.DependsOn {
+			// dependencies not registered are excluded by build tags
+			if dependency, ok := byName[dependency]; ok {
+				include(dependency)
+			}
+		}
+	}
+	for _, plugin := range all {
+		if len(enable) == 0 || plugin.matches(enable) {
+			include(plugin)
+		}
+	}
+	for _, plugin := range all {
+		if plugin.matches(disable) {
+			delete(enabled, plugin.Name)
+		}
+	}
+
+	var errs []string
+	for _, plugin := range all {
+		if !enabled[plugin.Name] {
+			continue
+		}
+		for _, dependency := range plugin.DependsOn {
+			if dependency, ok := byName[dependency]; ok && !enabled[dependency.Name] && len(dependency.Noop) == 0 {
+				errs = append(errs, fmt.Sprintf("plugin %s depends on disabled plugin %s", plugin.Name, dependency.Name))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+
+	owners := map[uintptr]Plugin{}
+	for _, plugin := range all {
+		for _, constructor := range plugin.Constructors {
+			if v := reflect.ValueOf(constructor); v.Kind() == reflect.Func {
+				owners[v.Pointer()] = plugin
+			}
+		}
+	}
+
+	registeredMu.RLock()
+	registry := append([]interface{}{}, Registry...)
+	registeredMu.RUnlock()
+
+	selected := []interface{}{}
+	substituted := map[string]bool{}
+	for _, constructor := range registry {
+		v := reflect.ValueOf(constructor)
+		if v.Kind() != reflect.Func {
+			selected = append(selected, constructor)
+			continue
+		}
+		plugin, owned := owners[v.Pointer()]
+		switch {
+		case !owned || enabled[plugin.Name]:
+			selected = append(selected, constructor)
+		case !substituted[plugin.Name]:
+			substituted[plugin.Name] = true
+			selected = append(selected, plugin.Noop...)
+		}
+	}
+	return selected, nil
+}
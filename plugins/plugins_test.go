@@ -0,0 +1,75 @@
+package plugins
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+type agent struct{ noop bool }
+type interceptor struct{ agent *agent }
+type server struct{}
+
+func newAgent() *agent                        { return &agent{} }
+func newNoopAgent() *agent                    { return &agent{noop: true} }
+func newInterceptor(a *agent) *interceptor    { return &interceptor{agent: a} }
+func newServer(i *interceptor) *server        { return &server{} }
+func newUnregistered() *struct{ name string } { return nil }
+
+func names(constructors []interface{}) string {
+	funcs := []string{}
+	for _, c := range constructors {
+		name := strings.Split(runtime.FuncForPC(reflect.ValueOf(c).Pointer()).Name(), ".")
+		funcs = append(funcs, name[len(name)-1])
+	}
+	return strings.Join(funcs, ",")
+}
+
+// restoreRegistry restores the registry after plugins are registered by the test
+func restoreRegistry(t *testing.T) {
+	registeredMu.RLock()
+	plugins, registry := append([]Plugin{}, registered...), append([]interface{}{}, Registry...)
+	registeredMu.RUnlock()
+	t.Cleanup(func() {
+		registeredMu.Lock()
+		defer registeredMu.Unlock()
+		registered, Registry = plugins, registry
+	})
+}
+
+func TestSelect(t *testing.T) {
+	restoreRegistry(t)
+	Register(Plugin{Name: "agent", Tags: []string{"observability"}, Constructors: []interface{}{newAgent}, Noop: []interface{}{newNoopAgent}})
+	Register(Plugin{Name: "interceptor", DependsOn: []string{"agent"}, Constructors: []interface{}{newInterceptor}})
+	Register(Plugin{Name: "server", DependsOn: []string{"interceptor", "excluded-by-tags"}, Constructors: []interface{}{newServer}})
+	Registry = append(Registry, newUnregistered)
+
+	cases := []struct {
+		enable   []string
+		disable  []string
+		expected string
+		err      string
+	}{
+		{expected: "newAgent,newInterceptor,newServer,newUnregistered"},
+		{disable: []string{"observability"}, expected: "newNoopAgent,newInterceptor,newServer,newUnregistered"},
+		{enable: []string{"interceptor"}, expected: "newAgent,newInterceptor,newUnregistered"},
+		{enable: []string{"server"}, disable: []string{"agent"}, expected: "newNoopAgent,newInterceptor,newServer,newUnregistered"},
+		{disable: []string{"interceptor"}, err: "plugin server depends on disabled plugin interceptor"},
+	}
+	for _, c := range cases {
+		selected, err := Select(c.enable, c.disable)
+		if c.err != "" {
+			if err == nil || err.Error() != c.err {
+				t.Fatalf("enable %v disable %v is not failed with %s but %v", c.enable, c.disable, c.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := names(selected); actual != c.expected {
+			t.Fatalf("enable %v disable %v selects %s instead of %s", c.enable, c.disable, actual, c.expected)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+//go:build pulsar
+// +build pulsar
+
+package pulsar
+
+import (
+	"time"
+
+	"github.com/shoplineapp/go-app/plugins/env"
+)
+
+// Config of the Pulsar client
+type Config struct {
+	URL               string        `env:"PULSAR_URL" required:"true"`
+	OperationTimeout  time.Duration `env:"PULSAR_OPERATION_TIMEOUT"`
+	ConnectionTimeout time.Duration `env:"PULSAR_CONNECTION_TIMEOUT"`
+}
+
+func LoadConfig(e *env.Env) (Config, error) {
+	config := Config{
+		OperationTimeout:  DEFAULT_PULSAR_OPERATION_TIMEOUT,
+		ConnectionTimeout: DEFAULT_PULSAR_CONNECTION_TIMEOUT,
+	}
+	err := e.Bind(&config)
+	return config, err
+}
@@ -34,8 +34,11 @@ type PulsarConsumer struct {
 	Handler PulsarConsumerInterface
 }
 
+// Component of consumer logs, see logger.SamplingPolicy
+const ConsumerLoggerComponent = "pulsar_consumer"
+
 type PulsarConsumerManager struct {
-	logger       *logger.Logger
+	logger       *logrus.Entry
 	pulsarServer *PulsarServer
 	consumers    map[string]*PulsarConsumer
 	IsStopped    bool
@@ -194,7 +197,7 @@ func NewPulsarConsumerManager(
 	params PulsarConsumerManagerParams,
 ) *PulsarConsumerManager {
 	cm := &PulsarConsumerManager{
-		logger:       params.Logger,
+		logger:       params.Logger.Component(ConsumerLoggerComponent),
 		pulsarServer: params.PulsarServer,
 		consumers:    map[string]*PulsarConsumer{},
 	}
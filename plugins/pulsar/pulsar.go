@@ -10,6 +10,7 @@ import (
 	ap "github.com/apache/pulsar-client-go/pulsar"
 	ap_log "github.com/apache/pulsar-client-go/pulsar/log"
 	"github.com/shoplineapp/go-app/plugins"
+	"github.com/shoplineapp/go-app/plugins/env"
 	"github.com/shoplineapp/go-app/plugins/logger"
 	"go.uber.org/fx"
 )
@@ -20,13 +21,17 @@ var (
 )
 
 func init() {
-	plugins.Registry = append(plugins.Registry,
-		[]interface{}{
+	plugins.Register(plugins.Plugin{
+		Name:      "pulsar",
+		Tags:      []string{"messaging"},
+		DependsOn: []string{"env", "logger"},
+		Constructors: []interface{}{
 			NewPulsarServer,
 			NewPulsarProducerManager,
 			NewPulsarConsumerManager,
-		}...,
-	)
+		},
+	})
+	env.Declare(NewPulsarServer, env.Requirement{Name: "PULSAR_URL", Description: "Service URL of ConnectWithConfig"})
 }
 
 type PulsarServer struct {
@@ -58,6 +63,15 @@ func (p *PulsarServer) Connect(URL string, opts ...PulsarClientOption) error {
 	return nil
 }
 
+// ConnectWithConfig connects with the config, e.g. from LoadConfig
+func (p *PulsarServer) ConnectWithConfig(config Config, opts ...PulsarClientOption) error {
+	opts = append([]PulsarClientOption{func(o *ap.ClientOptions) {
+		o.OperationTimeout = config.OperationTimeout
+		o.ConnectionTimeout = config.ConnectionTimeout
+	}}, opts...)
+	return p.Connect(config.URL, opts...)
+}
+
 func (p *PulsarServer) Shutdown() {
 	p.Close()
 }
@@ -15,7 +15,12 @@ import (
 )
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewPyroscopeAgent)
+	plugins.Register(plugins.Plugin{
+		Name:         "pyroscope",
+		Tags:         []string{"observability"},
+		Constructors: []interface{}{NewPyroscopeAgent},
+		Noop:         []interface{}{NewNoopPyroscopeAgent},
+	})
 }
 
 var (
@@ -25,8 +30,13 @@ var (
 )
 
 type PyroscopeAgent struct {
-	config  *pyroscope.Config
-	profile *pyroscope.Profiler
+	config   *pyroscope.Config
+	profile  *pyroscope.Profiler
+	disabled bool
+}
+
+func (p *PyroscopeAgent) Enabled() bool {
+	return !p.disabled
 }
 
 func (p *PyroscopeAgent) Configure(configs ...PyroscopeAgentConfigOption) error {
@@ -62,7 +72,7 @@ func (p *PyroscopeAgent) Configure(configs ...PyroscopeAgentConfigOption) error
 }
 
 func (p *PyroscopeAgent) Start() error {
-	if os.Getenv("ENABLE_PYROSCOPE") != "true" {
+	if p.disabled || os.Getenv("ENABLE_PYROSCOPE") != "true" {
 		return nil
 	}
 	if p.config == nil {
@@ -165,3 +175,8 @@ func NewPyroscopeAgent(lc fx.Lifecycle) *PyroscopeAgent {
 	})
 	return agent
 }
+
+// NewNoopPyroscopeAgent is provided when the plugin is disabled, it is never started
+func NewNoopPyroscopeAgent() *PyroscopeAgent {
+	return &PyroscopeAgent{disabled: true}
+}
@@ -0,0 +1,361 @@
+//go:build scheduler
+// +build scheduler
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"github.com/shoplineapp/go-app/common"
+	"github.com/shoplineapp/go-app/plugins"
+	"github.com/shoplineapp/go-app/plugins/logger"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/fx"
+)
+
+func init() {
+	plugins.Register(plugins.Plugin{
+		Name:         "scheduler",
+		Tags:         []string{"worker"},
+		DependsOn:    []string{"logger"},
+		Constructors: []interface{}{NewScheduler},
+	})
+}
+
+// Component of scheduler logs, see logger.SamplingPolicy
+const LoggerComponent = "scheduler"
+
+// OverlapPolicy decides what happens when a job is due while its previous run is running
+type OverlapPolicy int
+
+const (
+	// OverlapSkip skips the due run
+	OverlapSkip OverlapPolicy = iota
+	// OverlapQueue runs once more after the previous run, due runs are merged while one is queued
+	OverlapQueue
+	// OverlapReplace cancels the context of the previous run and runs after it returns
+	OverlapReplace
+)
+
+func (p OverlapPolicy) String() string {
+	switch p {
+	case OverlapSkip:
+		return "skip"
+	case OverlapQueue:
+		return "queue"
+	case OverlapReplace:
+		return "replace"
+	}
+	return fmt.Sprintf("OverlapPolicy(%d)", int(p))
+}
+
+// Job is a periodic task of the scheduler
+type Job struct {
+	Name string
+	// Cron is a standard 5 fields expression or a descriptor, e.g. "*/5 * * * *", "@hourly" or "CRON_TZ=Asia/Taipei 0 3 * * *"
+	Cron string
+	// Every is the fixed interval, instead of Cron
+	Every time.Duration
+	// Jitter delays each run by a random duration up to it, so replicas do not run at the same time
+	Jitter time.Duration
+	// Timeout is the deadline of the context of each run, no deadline by default
+	Timeout time.Duration
+	Overlap OverlapPolicy
+	// LeaderOnly runs the job on the leader only, whose context is cancelled when the leadership is lost
+	LeaderOnly bool
+	// Run is called with a context carrying a new trace id and a logger with the job name and trace id
+	Run func(ctx context.Context, log *logrus.Entry) error
+}
+
+// LeaderChecker elects the replica running leader only jobs, e.g. leader.Elector
+type LeaderChecker interface {
+	// Lead returns a context of ctx cancelled when the leadership is lost, or false if it is not the leader
+	Lead(ctx context.Context) (context.Context, bool)
+}
+
+// interval is the schedule of Every, unlike cron.Every it is not rounded to seconds
+type interval time.Duration
+
+func (i interval) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(i))
+}
+
+// Scheduler runs jobs from start until stop of the application
+type Scheduler struct {
+	logger *logrus.Entry
+	jobs   map[string]*job
+	leader LeaderChecker
+
+	mu      sync.Mutex
+	started bool
+	stopped bool
+	// schedule is cancelled on stop to stop scheduling, runs are cancelled if stop is timed out
+	schedule     context.Context
+	stopSchedule context.CancelFunc
+	runs         context.Context
+	cancelRuns   context.CancelFunc
+	// wg waits for the timers and runners, which wait for their runs
+	wg sync.WaitGroup
+}
+
+type job struct {
+	Job
+	schedule cron.Schedule
+	// triggers has a capacity of 1, which is the queued run
+	triggers chan struct{}
+
+	mu        sync.Mutex
+	isRunning bool
+	cancel    context.CancelFunc
+}
+
+type SchedulerParams struct {
+	fx.In
+
+	Lifecycle fx.Lifecycle
+	Logger    *logger.Logger
+	// Leader is provided by the leader plugin
+	Leader LeaderChecker `optional:"true"`
+}
+
+func NewScheduler(params SchedulerParams) *Scheduler {
+	s := &Scheduler{
+		logger: params.Logger.Component(LoggerComponent),
+		jobs:   map[string]*job{},
+		leader: params.Leader,
+	}
+	s.schedule, s.stopSchedule = context.WithCancel(context.Background())
+	s.runs, s.cancelRuns = context.WithCancel(context.Background())
+
+	params.Lifecycle.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			s.Start()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return s.Stop(ctx)
+		},
+	})
+	return s
+}
+
+// Add validates and adds the job, which is scheduled on start or immediately if the scheduler is started
+func (s *Scheduler) Add(j Job) error {
+	if j.Name == "" {
+		return errors.New("name of the job is required")
+	}
+	if j.Run == nil {
+		return fmt.Errorf("run of job %s is required", j.Name)
+	}
+	var schedule cron.Schedule
+	switch {
+	case j.Cron != "" && j.Every > 0:
+		return fmt.Errorf("job %s has both cron and every", j.Name)
+	case j.Cron != "":
+		parsed, err := cron.ParseStandard(j.Cron)
+		if err != nil {
+			return fmt.Errorf("invalid cron of job %s: %w", j.Name, err)
+		}
+		schedule = parsed
+	case j.Every > 0:
+		schedule = interval(j.Every)
+	default:
+		return fmt.Errorf("job %s has neither cron nor every", j.Name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if j.LeaderOnly && s.leader == nil {
+		return fmt.Errorf("job %s is leader only without a leader checker, see SetLeaderChecker", j.Name)
+	}
+	if _, exists := s.jobs[j.Name]; exists {
+		return fmt.Errorf("job %s is already added", j.Name)
+	}
+	if s.stopped {
+		return fmt.Errorf("scheduler is stopped, job %s is not added", j.Name)
+	}
+	added := &job{Job: j, schedule: schedule, triggers: make(chan struct{}, 1)}
+	s.jobs[j.Name] = added
+	if s.started {
+		s.launch(added)
+	}
+	return nil
+}
+
+// SetLeaderChecker sets the leader checker of leader only jobs, instead of the provided one
+func (s *Scheduler) SetLeaderChecker(leader LeaderChecker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.leader = leader
+}
+
+// Start schedules the added jobs
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started || s.stopped {
+		return
+	}
+	s.started = true
+	for _, j := range s.jobs {
+		s.launch(j)
+	}
+	s.logger.WithField("jobs", len(s.jobs)).Info("Scheduler started")
+}
+
+// Stop stops scheduling and waits for the running jobs, whose contexts are cancelled when ctx is done
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+
+	s.stopSchedule()
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		s.cancelRuns()
+		s.logger.Info("Scheduler stopped")
+		return nil
+	case <-ctx.Done():
+		s.cancelRuns()
+		<-done
+		s.logger.Warn("Scheduler stopped, running jobs are cancelled")
+		return ctx.Err()
+	}
+}
+
+// launch starts the timer and runner of the job
+func (s *Scheduler) launch(j *job) {
+	s.wg.Add(2)
+	go s.tick(j)
+	go s.runner(j)
+}
+
+// tick triggers the job when it is due
+func (s *Scheduler) tick(j *job) {
+	defer s.wg.Done()
+	for {
+		now := time.Now()
+		next := j.schedule.Next(now)
+		if next.IsZero() {
+			s.logger.WithField("job", j.Name).Warn("Job has no next run, unscheduled")
+			return
+		}
+		delay := next.Sub(now)
+		if j.Jitter > 0 {
+			delay += rand.N(j.Jitter)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-s.schedule.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.trigger(j)
+	}
+}
+
+// trigger dispatches the due run by the overlap policy
+func (s *Scheduler) trigger(j *job) {
+	j.mu.Lock()
+	running, cancel := j.isRunning, j.cancel
+	j.mu.Unlock()
+
+	if running {
+		switch j.Overlap {
+		case OverlapSkip:
+			s.logger.WithField("job", j.Name).Warn("Job is still running, the due run is skipped")
+			return
+		case OverlapReplace:
+			s.logger.WithField("job", j.Name).Warn("Job is still running, cancelled to be replaced")
+			cancel()
+		}
+	}
+	select {
+	case j.triggers <- struct{}{}:
+	default:
+		// a run is queued already
+	}
+}
+
+// runner runs the triggered runs of the job one by one
+func (s *Scheduler) runner(j *job) {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.schedule.Done():
+			return
+		case <-j.triggers:
+			s.run(j)
+		}
+	}
+}
+
+// run runs the job once with a trace id, logger, timeout and panic recovery
+func (s *Scheduler) run(j *job) {
+	traceID := uuid.New().String()
+	ctx, cancel := context.WithCancel(common.NewContextWithTraceID(s.runs, traceID))
+	defer cancel()
+	if j.LeaderOnly {
+		s.mu.Lock()
+		leader := s.leader
+		s.mu.Unlock()
+		leading, ok := leader.Lead(ctx)
+		if !ok {
+			s.logger.WithField("job", j.Name).Debug("Job is leader only, skipped on the follower")
+			return
+		}
+		ctx = leading
+	}
+	if j.Timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, j.Timeout)
+		defer cancelTimeout()
+	}
+	log := s.logger.WithFields(logrus.Fields{"job": j.Name, "trace_id": traceID})
+
+	j.mu.Lock()
+	j.isRunning, j.cancel = true, cancel
+	j.mu.Unlock()
+	defer func() {
+		j.mu.Lock()
+		j.isRunning, j.cancel = false, nil
+		j.mu.Unlock()
+	}()
+
+	start := time.Now()
+	log.Debug("Job started")
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+				log = log.WithField("stack", string(debug.Stack()))
+			}
+		}()
+		return j.Run(ctx, log)
+	}()
+
+	log = log.WithField("duration_ms", float64(time.Since(start).Microseconds())/1000)
+	switch {
+	case err != nil:
+		log.WithField("error", err).Error("Job failed")
+	case ctx.Err() == context.DeadlineExceeded:
+		log.Warn("Job finished after the timeout")
+	default:
+		log.Info("Job finished")
+	}
+}
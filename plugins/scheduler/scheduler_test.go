@@ -0,0 +1,191 @@
+//go:build scheduler
+// +build scheduler
+
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shoplineapp/go-app/apptest"
+	"github.com/shoplineapp/go-app/common"
+	"github.com/shoplineapp/go-app/plugins/scheduler"
+	"github.com/sirupsen/logrus"
+)
+
+func newScheduler(t *testing.T) (*apptest.App, *scheduler.Scheduler) {
+	var s *scheduler.Scheduler
+	a := apptest.New(t, apptest.WithPlugins(scheduler.NewScheduler))
+	a.Populate(&s)
+	return a, s
+}
+
+func TestSchedulerAdd(t *testing.T) {
+	_, s := newScheduler(t)
+	run := func(ctx context.Context, log *logrus.Entry) error { return nil }
+	for _, job := range []scheduler.Job{
+		{Name: "cron", Cron: "every minute", Run: run},
+		{Name: "none", Run: run},
+		{Name: "both", Cron: "@hourly", Every: time.Minute, Run: run},
+		{Name: "no-run", Every: time.Minute},
+	} {
+		if err := s.Add(job); err == nil {
+			t.Fatalf("invalid job %s is added", job.Name)
+		}
+	}
+	if err := s.Add(scheduler.Job{Name: "cron", Cron: "CRON_TZ=Asia/Taipei 0 3 * * *", Run: run}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(scheduler.Job{Name: "cron", Every: time.Minute, Run: run}); err == nil {
+		t.Fatal("job of the same name is added")
+	}
+}
+
+func TestSchedulerRun(t *testing.T) {
+	a, s := newScheduler(t)
+
+	var runs, panics, timeouts atomic.Int32
+	traced := make(chan string, 100)
+	jobs := []scheduler.Job{
+		{Name: "slow", Every: 10 * time.Millisecond, Overlap: scheduler.OverlapSkip, Run: func(ctx context.Context, log *logrus.Entry) error {
+			traced <- common.GetTraceID(ctx) + "=" + log.Data["trace_id"].(string)
+			runs.Add(1)
+			time.Sleep(35 * time.Millisecond)
+			return nil
+		}},
+		{Name: "panic", Every: 10 * time.Millisecond, Run: func(ctx context.Context, log *logrus.Entry) error {
+			panics.Add(1)
+			panic("boom")
+		}},
+		{Name: "timeout", Every: 10 * time.Millisecond, Timeout: 5 * time.Millisecond, Run: func(ctx context.Context, log *logrus.Entry) error {
+			<-ctx.Done()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				timeouts.Add(1)
+			}
+			return nil
+		}},
+	}
+	for _, job := range jobs {
+		if err := s.Add(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(120 * time.Millisecond)
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// runs of 35ms every 10ms skip the due runs in between
+	if n := runs.Load(); n < 2 || n > 4 {
+		t.Fatalf("slow job runs %d times", n)
+	}
+	if !a.Logged(logrus.WarnLevel, "the due run is skipped") {
+		t.Fatal("skipped runs are not logged")
+	}
+	if panics.Load() < 2 || !a.Logged(logrus.ErrorLevel, "Job failed") {
+		t.Fatalf("panicked job is not recovered, runs %d times", panics.Load())
+	}
+	if timeouts.Load() < 2 {
+		t.Fatalf("timeout of job is not applied, %d runs timed out", timeouts.Load())
+	}
+	close(traced)
+	seen := map[string]bool{}
+	for trace := range traced {
+		if seen[trace] || len(trace) != 73 {
+			t.Fatalf("trace id of the context and the logger %s is not new for each run", trace)
+		}
+		seen[trace] = true
+	}
+}
+
+func TestSchedulerOverlap(t *testing.T) {
+	_, s := newScheduler(t)
+
+	var queued, replaced, cancelled atomic.Int32
+	s.Add(scheduler.Job{Name: "queue", Every: 10 * time.Millisecond, Overlap: scheduler.OverlapQueue, Run: func(ctx context.Context, log *logrus.Entry) error {
+		queued.Add(1)
+		time.Sleep(25 * time.Millisecond)
+		return nil
+	}})
+	s.Add(scheduler.Job{Name: "replace", Every: 10 * time.Millisecond, Overlap: scheduler.OverlapReplace, Run: func(ctx context.Context, log *logrus.Entry) error {
+		replaced.Add(1)
+		select {
+		case <-ctx.Done():
+			cancelled.Add(1)
+		case <-time.After(time.Second):
+		}
+		return nil
+	}})
+	time.Sleep(105 * time.Millisecond)
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// queued runs follow each other without skipping
+	if n := queued.Load(); n < 3 || n > 5 {
+		t.Fatalf("queued job runs %d times", n)
+	}
+	if replaced.Load() < 5 || cancelled.Load() < replaced.Load()-1 {
+		t.Fatalf("replaced job runs %d times and %d are cancelled", replaced.Load(), cancelled.Load())
+	}
+}
+
+func TestSchedulerGracefulStop(t *testing.T) {
+	_, s := newScheduler(t)
+
+	started := make(chan struct{})
+	var cancelled atomic.Bool
+	s.Add(scheduler.Job{Name: "stuck", Every: time.Millisecond, Run: func(ctx context.Context, log *logrus.Entry) error {
+		close(started)
+		<-ctx.Done()
+		cancelled.Store(true)
+		return ctx.Err()
+	}})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) || !cancelled.Load() {
+		t.Fatalf("running job is not cancelled on timeout of stop, %v", err)
+	}
+	if err := s.Add(scheduler.Job{Name: "late", Every: time.Minute, Run: func(ctx context.Context, log *logrus.Entry) error { return nil }}); err == nil {
+		t.Fatal("job is added after stop")
+	}
+}
+
+type fakeLeader struct{ leading atomic.Bool }
+
+func (l *fakeLeader) Lead(ctx context.Context) (context.Context, bool) {
+	return ctx, l.leading.Load()
+}
+
+func TestSchedulerLeaderOnly(t *testing.T) {
+	_, s := newScheduler(t)
+	job := scheduler.Job{Name: "singleton", Every: 5 * time.Millisecond, LeaderOnly: true}
+	var runs atomic.Int32
+	job.Run = func(ctx context.Context, log *logrus.Entry) error {
+		runs.Add(1)
+		return nil
+	}
+	if err := s.Add(job); err == nil {
+		t.Fatal("leader only job is added without a leader checker")
+	}
+
+	leader := &fakeLeader{}
+	s.SetLeaderChecker(leader)
+	if err := s.Add(job); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if runs.Load() != 0 {
+		t.Fatalf("leader only job runs %d times on the follower", runs.Load())
+	}
+	leader.leading.Store(true)
+	time.Sleep(30 * time.Millisecond)
+	if runs.Load() == 0 {
+		t.Fatal("leader only job does not run on the leader")
+	}
+}
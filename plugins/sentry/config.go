@@ -0,0 +1,25 @@
+//go:build sentry
+// +build sentry
+
+package sentry
+
+import "github.com/shoplineapp/go-app/plugins/env"
+
+// Config of the Sentry client
+type Config struct {
+	DSN         string  `env:"SENTRY_DSN"`
+	Debug       bool    `env:"SENTRY_DEBUG"`
+	Environment string  `env:"ENVIRONMENT"`
+	Release     string  `env:"RELEASE"`
+	SampleRate  float64 `env:"SENTRY_SAMPLE_RATE" default:"1.0"`
+}
+
+// LoadConfig binds the config from env, the sample rate falls back to 1.0 if it is invalid or out of range
+func LoadConfig(e *env.Env) (Config, error) {
+	config := Config{SampleRate: 1.0}
+	err := e.Bind(&config)
+	if config.SampleRate < 0.0 || config.SampleRate > 1.0 {
+		config.SampleRate = 1.0
+	}
+	return config, err
+}
@@ -7,7 +7,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strconv"
 
 	"github.com/getsentry/sentry-go"
 
@@ -19,12 +18,29 @@ import (
 var ErrSentryNotInitialized = errors.New("missing environment variable SENTRY_DSN: sentry is not initialized")
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewSentryAgent)
+	plugins.Register(plugins.Plugin{
+		Name:         "sentry",
+		Tags:         []string{"observability"},
+		DependsOn:    []string{"env", "logger"},
+		Constructors: []interface{}{NewSentryAgent},
+		Noop:         []interface{}{NewNoopSentryAgent},
+	})
+	env.Declare(NewSentryAgent,
+		env.Requirement{Name: "SENTRY_DSN", Secret: true, Description: "DSN of the Sentry project, Sentry is not initialized without it"},
+		env.Requirement{Name: "SENTRY_DEBUG", Description: "Debug mode of the Sentry client"},
+		env.Requirement{Name: "SENTRY_SAMPLE_RATE", Default: "1.0", Description: "Sample rate of error events"},
+		env.Requirement{Name: "RELEASE", Description: "Release reported to Sentry"},
+	)
 }
 
 type SentryAgent struct {
-	env    *env.Env
-	logger *logger.Logger
+	env      *env.Env
+	logger   *logger.Logger
+	disabled bool
+}
+
+func (a *SentryAgent) Enabled() bool {
+	return !a.disabled
 }
 
 // ConfigOption is a function that configures sentry.ClientOptions
@@ -32,7 +48,14 @@ type ConfigOption func(*sentry.ClientOptions)
 
 // Configure initializes Sentry using environment variables with optional overrides
 func (a *SentryAgent) Configure(opts ...ConfigOption) error {
-	dsn := a.env.GetEnv("SENTRY_DSN")
+	if a.disabled {
+		return nil
+	}
+	config, err := LoadConfig(a.env)
+	if err != nil {
+		a.logger.WithField("error", err).Warn("Invalid Sentry configuration, fallback to default")
+	}
+	dsn := config.DSN
 	if dsn == "" {
 		a.logger.Warn("SENTRY_DSN not set, Sentry will not be initialized")
 		return ErrSentryNotInitialized
@@ -41,11 +64,11 @@ func (a *SentryAgent) Configure(opts ...ConfigOption) error {
 	// Default options from environment variables
 	options := sentry.ClientOptions{
 		Dsn:            dsn,
-		Debug:          a.env.GetEnv("SENTRY_DEBUG") == "true",
-		Environment:    a.env.GetEnv("ENVIRONMENT"),
-		Release:        a.env.GetEnv("RELEASE"),
+		Debug:          config.Debug,
+		Environment:    config.Environment,
+		Release:        config.Release,
 		SendDefaultPII: true,
-		SampleRate:     a.parseSampleRate(),
+		SampleRate:     config.SampleRate,
 	}
 	for _, opt := range opts {
 		opt(&options)
@@ -68,19 +91,9 @@ func NewSentryAgent(env *env.Env, logger *logger.Logger) *SentryAgent {
 	}
 }
 
-func (a *SentryAgent) parseSampleRate() float64 {
-	rate := a.env.GetEnv("SENTRY_SAMPLE_RATE")
-	if rate == "" {
-		return 1.0
-	}
-	parsed, err := strconv.ParseFloat(rate, 64)
-	if err != nil {
-		return 1.0
-	}
-	if parsed >= 0.0 && parsed <= 1.0 {
-		return parsed
-	}
-	return 1.0
+// NewNoopSentryAgent is provided when the plugin is disabled, it is never initialized so events are dropped
+func NewNoopSentryAgent() *SentryAgent {
+	return &SentryAgent{disabled: true}
 }
 
 // HubFromContext returns the Sentry hub from context, or clones the current hub if not found.
@@ -0,0 +1,17 @@
+//go:build sqs
+// +build sqs
+
+package sqs
+
+import "github.com/shoplineapp/go-app/plugins/env"
+
+// Config of the AWS topic manager
+type Config struct {
+	Region string `env:"AWS_REGION"`
+}
+
+func LoadConfig(e *env.Env) (Config, error) {
+	var config Config
+	err := e.Bind(&config)
+	return config, err
+}
@@ -28,14 +28,22 @@ type AwsTopicManager struct {
 }
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewAwsTopicManager)
+	plugins.Register(plugins.Plugin{
+		Name:         "sqs",
+		Tags:         []string{"aws"},
+		DependsOn:    []string{"env"},
+		Constructors: []interface{}{NewAwsTopicManager},
+	})
+	env.Declare(NewAwsTopicManager, env.Requirement{Name: "AWS_REGION", Description: "AWS region of the topics, which can be set later by SetRegion"})
 }
 
 func NewAwsTopicManager(env *env.Env) *AwsTopicManager {
 	topicMaps := make(map[string]*Topic)
+	// the region is optional, which can be set later by SetRegion
+	config, _ := LoadConfig(env)
 	mgr := &AwsTopicManager{
 		TopicMaps: topicMaps,
-		Region:    env.GetEnv("AWS_REGION"),
+		Region:    config.Region,
 	}
 
 	return mgr
@@ -22,20 +22,28 @@ type AwsSqsWorker struct {
 	wg     *sync.WaitGroup
 
 	topicMgr *sqs.AwsTopicManager
-	logger   *logger.Logger
+	logger   *log.Entry
 	handler  EventHandlerInterface
 
 	enabled bool
 	started bool
 }
 
+// Component of worker logs, see logger.SamplingPolicy
+const LoggerComponent = "sqs_worker"
+
 type awsMessage struct {
 	topicName string
 	*aws_sqs.Message
 }
 
 func init() {
-	plugins.Registry = append(plugins.Registry, NewAwsSqsWorker)
+	plugins.Register(plugins.Plugin{
+		Name:         "sqs-worker",
+		Tags:         []string{"aws", "worker"},
+		DependsOn:    []string{"sqs", "logger"},
+		Constructors: []interface{}{NewAwsSqsWorker},
+	})
 }
 
 func (w *AwsSqsWorker) SetRegion(region string) {
@@ -191,7 +199,7 @@ func NewAwsSqsWorker(lc fx.Lifecycle, topicMgr *sqs.AwsTopicManager, logger *log
 		cancel:   cancel,
 		wg:       new(sync.WaitGroup),
 		topicMgr: topicMgr,
-		logger:   logger,
+		logger:   logger.Component(LoggerComponent),
 
 		enabled: true,
 		started: false,
@@ -0,0 +1,141 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/shoplineapp/go-app/plugins/env"
+	"go.uber.org/fx"
+)
+
+// Role is a process role of the application, e.g. api or worker, with its own invokes, plugins and modules
+// in addition to the ones of the application
+type Role struct {
+	name           string
+	funcs          []interface{}
+	enablePlugins  []string
+	disablePlugins []string
+	modules        []AppModuleInterface
+	options        []fx.Option
+}
+
+func (r *Role) Name() string {
+	return r.name
+}
+
+// EnablePlugins provides only the registered plugins of the names or tags and their dependencies for the role
+func (r *Role) EnablePlugins(names ...string) *Role {
+	r.enablePlugins = append(r.enablePlugins, names...)
+	return r
+}
+
+// DisablePlugins substitutes the registered plugins of the names or tags with their noop implementations for the role
+func (r *Role) DisablePlugins(names ...string) *Role {
+	r.disablePlugins = append(r.disablePlugins, names...)
+	return r
+}
+
+// AddModule adds the module to the role only
+func (r *Role) AddModule(module AppModuleInterface) *Role {
+	r.modules = append(r.modules, module)
+	return r
+}
+
+// AddOptions appends fx options to the role only
+func (r *Role) AddOptions(options ...fx.Option) *Role {
+	r.options = append(r.options, options...)
+	return r
+}
+
+// Role defines a process role with the funcs invoked after the ones given to Run. The role is selected by
+// SetRole, the --role flag or APP_ROLE, so one binary runs as any of the roles.
+//
+//	app.Role("api", func(grpc *presets.DefaultGrpcServerWithNewrelic) {})
+//	app.Role("worker", func(worker *sqs_worker.AwsSqsWorker) {}).DisablePlugins("grpc")
+//	app.Run()
+func (app *Application) Role(name string, funcs ...interface{}) *Role {
+	for _, role := range app.roles {
+		if role.name == name {
+			role.funcs = append(role.funcs, funcs...)
+			return role
+		}
+	}
+	role := &Role{name: name, funcs: funcs}
+	app.roles = append(app.roles, role)
+	return role
+}
+
+// Roles returns the names of defined roles
+func (app *Application) Roles() []string {
+	names := make([]string, len(app.roles))
+	for i, role := range app.roles {
+		names[i] = role.name
+	}
+	return names
+}
+
+// SetRole selects the role, which takes precedence over the --role flag and APP_ROLE
+func (app *Application) SetRole(name string) {
+	app.role = name
+}
+
+// selectedRole returns the selected role, which is required once any role is defined unless optional
+func (app *Application) selectedRole(optional bool) (*Role, error) {
+	if len(app.roles) == 0 {
+		return nil, nil
+	}
+
+	name := app.role
+	if name == "" {
+		name, _ = roleFlag(os.Args[1:])
+	}
+	if name == "" {
+		name = env.Lookup("APP_ROLE")
+	}
+	if name == "" && optional {
+		return nil, nil
+	}
+	if name == "" {
+		return nil, fmt.Errorf("role is not selected by APP_ROLE or --role, one of %s", strings.Join(app.Roles(), ", "))
+	}
+	for _, role := range app.roles {
+		if role.name == name {
+			return role, nil
+		}
+	}
+	return nil, fmt.Errorf("role %s is not defined, one of %s", name, strings.Join(app.Roles(), ", "))
+}
+
+// roleFlag returns the value of --role=<name> or --role <name>, and the args without the flag
+func roleFlag(args []string) (string, []string) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		if len(arg)-len(name) == 0 || len(arg)-len(name) > 2 {
+			continue
+		}
+		rest := append([]string{}, args[:i]...)
+		if value, found := strings.CutPrefix(name, "role="); found {
+			return value, append(rest, args[i+1:]...)
+		}
+		if name == "role" && i+1 < len(args) {
+			return args[i+1], append(rest, args[i+2:]...)
+		}
+	}
+	return "", args
+}
+
+// ValidateRoles validates the dependency graph of every role, e.g. in a test of the main package
+func (app *Application) ValidateRoles(funcs ...interface{}) error {
+	var errs []error
+	for _, role := range app.roles {
+		if err := fx.ValidateApp(app.build(role, funcs)); err != nil {
+			errs = append(errs, fmt.Errorf("role %s: %w", role.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,34 @@
+package app_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shoplineapp/go-app/apptest"
+)
+
+type worker struct{}
+
+func TestRole(t *testing.T) {
+	var invoked []string
+	a := apptest.New(t, apptest.WithEnv(map[string]string{"APP_ROLE": "worker"}))
+	a.Role("api", func() { invoked = append(invoked, "api") })
+	a.Role("worker", func() { invoked = append(invoked, "worker") }).
+		AddModule(&storageModule{events: &[]string{}})
+	a.Start(func() { invoked = append(invoked, "common") })
+
+	if strings.Join(invoked, ",") != "common,worker" {
+		t.Fatalf("invokes of the selected role are not run but %v", invoked)
+	}
+
+	a.Role("api", func(*worker) {})
+	err := a.ValidateRoles()
+	if err == nil || !strings.Contains(err.Error(), "role api: ") || strings.Contains(err.Error(), "role worker") {
+		t.Fatalf("roles are not validated but %v", err)
+	}
+
+	a.SetRole("scheduler")
+	if err := a.Validate(); err == nil || err.Error() != "role scheduler is not defined, one of api, worker" {
+		t.Fatalf("unknown role is not reported but %v", err)
+	}
+}